@@ -42,3 +42,13 @@ func (c *Config) SetupConfig(path string) {
 		log.Fatal("SetupConfig() could not decode toml, err: ", err)
 	}
 }
+
+// FieldSets returns the fields to parse for each type of Bro log, keyed
+// by the name of their [parser] section (ex: "conn", "dns").
+func (c *Config) FieldSets() map[string][]string {
+	fieldSets := make(map[string][]string, len(c.Parser))
+	for logType, p := range c.Parser {
+		fieldSets[logType] = p.Fields
+	}
+	return fieldSets
+}
@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// AddrFamily is the IP version of a Bro addr value.
+type AddrFamily int
+
+// Address families of a Bro addr value.
+const (
+	IPv4 AddrFamily = 4
+	IPv6 AddrFamily = 6
+)
+
+// Addr is a parsed Bro addr value. IP never carries a zone, the zone of
+// a link-local IPv6 address (ex: "eth0" in "fe80::1%eth0") is kept in Zone.
+type Addr struct {
+	IP     netip.Addr
+	Zone   string
+	Family AddrFamily
+}
+
+// ParseAddr parses a Bro addr value. Unlike net.ParseIP it accepts IPv6
+// addresses with a zone, and IPv4-mapped IPv6 addresses
+// (ex: "::ffff:1.2.3.4") are normalized to their IPv4 address.
+func ParseAddr(s string) (Addr, error) {
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return Addr{}, errors.New("Invalid addr value: " + s)
+	}
+
+	addr := Addr{Zone: ip.Zone()}
+	ip = ip.WithZone("").Unmap()
+	addr.IP = ip
+
+	if ip.Is4() {
+		addr.Family = IPv4
+	} else {
+		addr.Family = IPv6
+	}
+
+	return addr, nil
+}
+
+// String returns the address in its canonical form, including its zone.
+func (a Addr) String() string {
+	if a.Zone != "" {
+		return a.IP.WithZone(a.Zone).String()
+	}
+	return a.IP.String()
+}
+
+// CanonicalizeAddr returns a Bro addr value in its canonical form, so that
+// equal addresses are equal strings when grouping or joining on them:
+// IPv4-mapped IPv6 addresses become their IPv4 address (ex: "::ffff:1.2.3.4"
+// is "1.2.3.4"), and IPv6 addresses are compressed and lowercase (ex:
+// "2001:DB8:0:0::1" is "2001:db8::1").
+func CanonicalizeAddr(s string) (string, error) {
+	addr, err := ParseAddr(s)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// groupKey returns a value to group rows by, canonicalized if it is an
+// addr. Values that aren't valid addrs, ex: unset ones, are kept as is.
+func groupKey(isAddr bool, value string) string {
+	if !isAddr {
+		return value
+	}
+	if canonical, err := CanonicalizeAddr(value); err == nil {
+		return canonical
+	}
+	return value
+}
+
+// isAddrField reports whether the field parsed at index is of type addr.
+func (p *Parser) isAddrField(index int) bool {
+	types, err := p.rowTypes()
+	return err == nil && index < len(types) && types[index] == "addr"
+}
@@ -0,0 +1,53 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	addr, err := ParseAddr("10.1.20.227")
+	assert.Nil(err)
+	assert.Equal(addr.Family, IPv4, "parsed family incorrectly")
+	assert.Equal(addr.String(), "10.1.20.227", "parsed addr incorrectly")
+
+	addr, err = ParseAddr("fe80::1%eth0")
+	assert.Nil(err)
+	assert.Equal(addr.Family, IPv6, "parsed family incorrectly")
+	assert.Equal(addr.Zone, "eth0", "parsed zone incorrectly")
+	assert.Equal(addr.IP.String(), "fe80::1", "IP should not carry the zone")
+	assert.Equal(addr.String(), "fe80::1%eth0", "parsed addr incorrectly")
+
+	addr, err = ParseAddr("::ffff:1.2.3.4")
+	assert.Nil(err)
+	assert.Equal(addr.Family, IPv4, "IPv4-mapped addresses should be IPv4")
+	assert.Equal(addr.String(), "1.2.3.4", "IPv4-mapped addresses should be normalized")
+
+	addr, err = ParseAddr("2001:0db8:0000:0000:0000:0000:0000:0001")
+	assert.Nil(err)
+	assert.Equal(addr.String(), "2001:db8::1", "IPv6 should be compressed")
+
+	_, err = ParseAddr("-")
+	assert.NotNil(err, "expected an error for an unset addr")
+}
+
+func TestCanonicalizeAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	for addr, canonical := range map[string]string{
+		"1.2.3.4":         "1.2.3.4",
+		"::ffff:1.2.3.4":  "1.2.3.4",
+		"2001:DB8:0:0::1": "2001:db8::1",
+		"fe80::0001%eth0": "fe80::1%eth0",
+	} {
+		value, err := CanonicalizeAddr(addr)
+		assert.Nil(err)
+		assert.Equal(value, canonical, "canonicalized "+addr+" incorrectly")
+	}
+
+	_, err := CanonicalizeAddr("-")
+	assert.NotNil(err, "expected an error for an unset addr")
+}
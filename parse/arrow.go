@@ -0,0 +1,306 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// arrowColumn describes how a Bro field is stored in Arrow.
+type arrowColumn struct {
+	name        string
+	elementType string
+	dataType    arrow.DataType
+	list        bool
+}
+
+// arrowColumnFor maps a Bro type to an Arrow column. Sets and vectors
+// become lists of their element type.
+func arrowColumnFor(name, broType string) arrowColumn {
+
+	column := arrowColumn{name: name, elementType: broType}
+	if isContainerType(broType) {
+		column.list = true
+		column.elementType = containerElementType(broType)
+	}
+
+	switch column.elementType {
+	case "bool":
+		column.dataType = arrow.FixedWidthTypes.Boolean
+	case "count":
+		column.dataType = arrow.PrimitiveTypes.Uint64
+	case "int":
+		column.dataType = arrow.PrimitiveTypes.Int64
+	case "port":
+		column.dataType = arrow.PrimitiveTypes.Uint16
+	case "double":
+		column.dataType = arrow.PrimitiveTypes.Float64
+	case "time":
+		column.dataType = arrow.FixedWidthTypes.Timestamp_us
+	case "interval":
+		column.dataType = arrow.FixedWidthTypes.Duration_us
+	default:
+		column.dataType = arrow.BinaryTypes.String
+	}
+
+	return column
+}
+
+// field returns the field of the column in the schema.
+func (c arrowColumn) field() arrow.Field {
+	if c.list {
+		return arrow.Field{Name: c.name, Type: arrow.ListOf(c.dataType), Nullable: true}
+	}
+	return arrow.Field{Name: c.name, Type: c.dataType, Nullable: true}
+}
+
+// ArrowSink writes rows to w as an Arrow IPC stream, for handing Bro logs
+// to Arrow based tools such as Polars, DataFusion or pandas without an
+// intermediate format. Column types are mapped from the #types header:
+// counts, ints and ports are integers, doubles are floats, times are UTC
+// timestamps and intervals durations, both in microseconds, sets and
+// vectors are lists and any other type is a UTF-8 string. Unset values are
+// nulls. Rows are buffered and every batch is written as a record batch.
+// Close must be called to end the stream.
+type ArrowSink struct {
+	writer    *ipc.Writer
+	builder   *array.RecordBuilder
+	columns   []arrowColumn
+	batchSize int
+	batch     [][]interface{}
+	numRows   int64
+	batches   int
+	markers   FieldMarkers
+}
+
+// NewArrowSink returns a sink writing fields of the given Bro types to w,
+// ex: the fields and types returned by Schema. Batches hold 10000 rows by
+// default.
+func NewArrowSink(w io.Writer, fields, types []string) (*ArrowSink, error) {
+
+	if len(fields) != len(types) {
+		return nil, errors.New("Fields and types must be the same length")
+	}
+
+	s := &ArrowSink{batchSize: 10000, markers: DefaultFieldMarkers}
+	schemaFields := make([]arrow.Field, len(fields))
+	for i, field := range fields {
+		s.columns = append(s.columns, arrowColumnFor(field, types[i]))
+		schemaFields[i] = s.columns[i].field()
+	}
+
+	schema := arrow.NewSchema(schemaFields, nil)
+	s.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	s.writer = ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator))
+
+	return s, nil
+}
+
+// SetBatchSize sets the number of rows written in each record batch.
+func (s *ArrowSink) SetBatchSize(size int) {
+	s.batchSize = size
+}
+
+// SetFieldMarkers sets the unset, empty and set separator markers of the
+// rows written, ex: the ones returned by the FieldMarkers of their parser.
+func (s *ArrowSink) SetFieldMarkers(markers FieldMarkers) {
+	s.markers = markers.orDefault()
+}
+
+// Write converts and buffers a row, and writes a record batch once the
+// batch is full. A row with a value that doesn't match its type is
+// rejected, and the rows buffered before it are kept.
+func (s *ArrowSink) Write(row []string) error {
+
+	if len(row) != len(s.columns) {
+		return errors.New("Row has " + strconv.Itoa(len(row)) + " values but there are " + strconv.Itoa(len(s.columns)) + " columns")
+	}
+
+	values := make([]interface{}, len(row))
+	for i, column := range s.columns {
+		value, err := column.convert(row[i], s.markers)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	s.batch = append(s.batch, values)
+	if len(s.batch) >= s.batchSize {
+		return s.flush()
+	}
+
+	return nil
+}
+
+// Close writes any buffered rows and the end of the stream. The underlying
+// writer is not closed.
+func (s *ArrowSink) Close() error {
+
+	defer s.builder.Release()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	return s.writer.Close()
+}
+
+// flush writes the buffered rows as a record batch.
+func (s *ArrowSink) flush() error {
+
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	for _, values := range s.batch {
+		for i, value := range values {
+			appendArrowValue(s.builder.Field(i), value)
+		}
+	}
+
+	record := s.builder.NewRecordBatch()
+	defer record.Release()
+
+	s.numRows += int64(len(s.batch))
+	s.batches++
+	s.batch = s.batch[:0]
+
+	return s.writer.Write(record)
+}
+
+// convert converts a value of the column: nil for unset values, a slice of
+// its elements for lists, without any for empty sets, or else a single
+// value of the element type of the column.
+func (c arrowColumn) convert(value string, markers FieldMarkers) (interface{}, error) {
+
+	if !c.list {
+		if value == markers.Empty && c.dataType.ID() == arrow.STRING {
+			return "", nil
+		}
+		return c.convertElement(value, markers.Unset)
+	}
+
+	switch value {
+	case markers.Unset:
+		return nil, nil
+	case markers.Empty:
+		return []interface{}{}, nil
+	}
+
+	var elements []interface{}
+	for _, element := range strings.Split(value, markers.SetSeparator) {
+		converted, err := c.convertElement(element, markers.Unset)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, converted)
+	}
+
+	return elements, nil
+}
+
+// convertElement converts a single value of the element type of the
+// column. Values equal to unset are nil.
+func (c arrowColumn) convertElement(value, unset string) (interface{}, error) {
+
+	if value == unset {
+		return nil, nil
+	}
+
+	switch c.elementType {
+	case "bool":
+		if value != "T" && value != "F" {
+			return nil, c.invalid(value)
+		}
+		return value == "T", nil
+
+	case "count":
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return v, nil
+
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return v, nil
+
+	case "port":
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return uint16(port), nil
+
+	case "double":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return v, nil
+
+	case "time":
+		ts, err := ParseTime(value)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return arrow.Timestamp(ts.UnixMicro()), nil
+
+	case "interval":
+		duration, err := ParseInterval(value)
+		if err != nil {
+			return nil, c.invalid(value)
+		}
+		return arrow.Duration(duration.Microseconds()), nil
+	}
+
+	return value, nil
+}
+
+// appendArrowValue appends a value converted by convert to builder.
+func appendArrowValue(builder array.Builder, value interface{}) {
+
+	if value == nil {
+		builder.AppendNull()
+		return
+	}
+
+	switch b := builder.(type) {
+	case *array.ListBuilder:
+		b.Append(true)
+		for _, element := range value.([]interface{}) {
+			appendArrowValue(b.ValueBuilder(), element)
+		}
+	case *array.BooleanBuilder:
+		b.Append(value.(bool))
+	case *array.Uint64Builder:
+		b.Append(value.(uint64))
+	case *array.Int64Builder:
+		b.Append(value.(int64))
+	case *array.Uint16Builder:
+		b.Append(value.(uint16))
+	case *array.Float64Builder:
+		b.Append(value.(float64))
+	case *array.TimestampBuilder:
+		b.Append(value.(arrow.Timestamp))
+	case *array.DurationBuilder:
+		b.Append(value.(arrow.Duration))
+	case *array.StringBuilder:
+		b.Append(value.(string))
+	}
+}
+
+// invalid returns the error of a value that doesn't match its type.
+func (c arrowColumn) invalid(value string) error {
+	return errors.New("Value " + value + " of " + c.name + " is not a valid " + c.elementType)
+}
@@ -0,0 +1,137 @@
+package parse
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrowSink(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	sink, err := NewArrowSink(&out,
+		[]string{"ts", "uid", "id.orig_p", "orig_bytes", "duration", "tunnel_parents"},
+		[]string{"time", "string", "port", "count", "interval", "set[string]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.SetBatchSize(2)
+
+	rows := make(chan []string, 3)
+	rows <- []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "443", "100", "0.000345", "C1,C2"}
+	rows <- []string{"1452684904.908400", "(empty)", "-", "-", "-", "(empty)"}
+	rows <- []string{"1452684905.908400", "C7fIlMZDuRiqjpYbb", "53", "0", "1.5", "-"}
+	close(rows)
+
+	if err := Drain(rows, sink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(sink.numRows, int64(3), "all rows should be written")
+
+	reader, err := ipc.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Release()
+
+	// The schema holds a field per column, with lists for sets
+	schema := reader.Schema()
+	assert.Equal(len(schema.Fields()), 6, "expected every column in the schema")
+	assert.Equal(schema.Field(0).Type, arrow.DataType(arrow.FixedWidthTypes.Timestamp_us), "expected times to be timestamps")
+	assert.Equal(schema.Field(2).Type.ID(), arrow.UINT16, "expected ports to be 16 bit integers")
+	assert.Equal(schema.Field(5).Name, "tunnel_parents", "expected the name of the field")
+	assert.Equal(schema.Field(5).Type.ID(), arrow.LIST, "expected sets to be lists")
+
+	var lengths []int64
+	var uids, parents []string
+	var ports []uint16
+	var counts []uint64
+	var durations []arrow.Duration
+	var ts []time.Time
+	var nullPorts, nullParents int
+	for reader.Next() {
+		record := reader.RecordBatch()
+		lengths = append(lengths, record.NumRows())
+
+		times := record.Column(0).(*array.Timestamp)
+		uidColumn := record.Column(1).(*array.String)
+		portColumn := record.Column(2).(*array.Uint16)
+		countColumn := record.Column(3).(*array.Uint64)
+		durationColumn := record.Column(4).(*array.Duration)
+		lists := record.Column(5).(*array.List)
+		elements := lists.ListValues().(*array.String)
+
+		for i := 0; i < int(record.NumRows()); i++ {
+			ts = append(ts, times.Value(i).ToTime(arrow.Microsecond))
+			uids = append(uids, uidColumn.Value(i))
+			ports = append(ports, portColumn.Value(i))
+			counts = append(counts, countColumn.Value(i))
+			durations = append(durations, durationColumn.Value(i))
+			if portColumn.IsNull(i) {
+				nullPorts++
+			}
+			if lists.IsNull(i) {
+				nullParents++
+				continue
+			}
+			start, end := lists.ValueOffsets(i)
+			for j := start; j < end; j++ {
+				parents = append(parents, elements.Value(int(j)))
+			}
+		}
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(lengths, []int64{2, 1}, "expected a record batch per batch")
+	assert.Equal(ts[0], time.UnixMicro(1452684903908400).UTC(), "expected the time in microseconds")
+	assert.Equal(uids, []string{"CbOiIv2wbbH7F25W21", "", "C7fIlMZDuRiqjpYbb"}, "expected empty strings for empty values")
+	assert.Equal(ports[0], uint16(443), "expected the port")
+	assert.Equal(counts[0], uint64(100), "expected the count")
+	assert.Equal(durations[2], arrow.Duration(1500000), "expected the interval in microseconds")
+	assert.Equal(nullPorts, 1, "expected the unset port to be a null")
+	assert.Equal(parents, []string{"C1", "C2"}, "expected the elements of the set, and none for the empty set")
+	assert.Equal(nullParents, 1, "expected the unset set to be a null")
+}
+
+func TestArrowSinkInvalidValue(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	sink, err := NewArrowSink(&out, []string{"uid", "orig_bytes"}, []string{"string", "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.SetBatchSize(2)
+
+	assert.Nil(sink.Write([]string{"C1", "100"}))
+	assert.EqualError(sink.Write([]string{"C2", "lots"}), "Value lots of orig_bytes is not a valid count", "expected an error for a value that doesn't match its type")
+	assert.Nil(sink.Write([]string{"C3", "200"}), "the rows after an invalid row should still be written")
+	assert.Nil(sink.Write([]string{"C4", "-"}))
+	assert.Nil(sink.Close())
+	assert.Equal(sink.numRows, int64(3), "expected the invalid row to be left out")
+
+	reader, err := ipc.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Release()
+
+	var uids []string
+	for reader.Next() {
+		record := reader.RecordBatch()
+		column := record.Column(0).(*array.String)
+		for i := 0; i < column.Len(); i++ {
+			uids = append(uids, column.Value(i))
+		}
+	}
+	assert.Nil(reader.Err())
+	assert.Equal(uids, []string{"C1", "C3", "C4"}, "expected the valid rows")
+}
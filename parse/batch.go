@@ -0,0 +1,43 @@
+package parse
+
+// Batches parses the Bro log in the background, and sends its rows in
+// batches of size rows, ex: for bulk inserts. The last batch holds the rows
+// left at the end of the log, and may be smaller. Rows are the same as the
+// ones BufferRow pushes, and the channel is closed once parsing is done,
+// along with p.Errors like BufferRow does.
+func (p *Parser) Batches(size int) <-chan [][]string {
+
+	if size < 1 {
+		size = 1
+	}
+
+	batches := make(chan [][]string)
+
+	go func() {
+		batch := make([][]string, 0, size)
+
+		err := p.eachSelectedRow(func(lineNum int, row []string) error {
+			batch = append(batch, p.output(row, row))
+			if len(batch) == size {
+				p.waitIfPaused()
+				batches <- batch
+				batch = make([][]string, 0, size)
+			}
+			return nil
+		})
+		if err != nil {
+			p.reportError(err)
+		}
+
+		if len(batch) > 0 {
+			batches <- batch
+		}
+
+		close(batches)
+		if p.Errors != nil {
+			close(p.Errors)
+		}
+	}()
+
+	return batches
+}
@@ -0,0 +1,30 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid"})
+
+	var sizes []int
+	var rows [][]string
+	for batch := range parser.Batches(3) {
+		sizes = append(sizes, len(batch))
+		rows = append(rows, batch...)
+	}
+
+	assert.Equal(sizes, []int{3, 1}, "expected full batches and a partial last batch")
+
+	all, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, all, "batches should hold every row in order")
+}
@@ -0,0 +1,56 @@
+package parse
+
+// blockedField is a blocklist resolved to the index of its field.
+type blockedField struct {
+	index  int
+	values map[string]bool
+}
+
+// SetBlocklist skips the rows whose value of field is one of values, ex:
+// every row with a service of "dns", or from a set of noisy addresses. It
+// complements SetFilter with a fast lookup of the values in a set, and
+// applies before it. Blocklists of several fields can be set, and setting
+// the blocklist of a field again replaces it. An empty list removes it.
+// field must be one of the fields parsed.
+func (p *Parser) SetBlocklist(field string, values []string) {
+
+	if len(values) == 0 {
+		delete(p.blocklists, field)
+		return
+	}
+
+	blocked := make(map[string]bool, len(values))
+	for _, value := range values {
+		blocked[value] = true
+	}
+
+	if p.blocklists == nil {
+		p.blocklists = make(map[string]map[string]bool)
+	}
+	p.blocklists[field] = blocked
+}
+
+// resolveBlocklists returns the blocklists by the index of their field in
+// the fields parsed.
+func (p *Parser) resolveBlocklists() ([]blockedField, error) {
+
+	var resolved []blockedField
+	for field, values := range p.blocklists {
+		index, err := getIndex(p.fields, field)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, blockedField{index: index, values: values})
+	}
+	return resolved, nil
+}
+
+// isBlocked reports whether a row has a value in one of the blocklists.
+func isBlocked(blocklists []blockedField, row []string) bool {
+	for _, blocked := range blocklists {
+		if blocked.index < len(row) && blocked.values[row[blocked.index]] {
+			return true
+		}
+	}
+	return false
+}
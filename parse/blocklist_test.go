@@ -0,0 +1,32 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBlocklist(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"id.orig_h", "proto"})
+	parser.SetBlocklist("proto", []string{"udp", "icmp"})
+	parser.SetBlocklist("id.orig_h", []string{"10.1.20.229"})
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"10.1.20.227", "tcp"}}, "expected blocked rows to be skipped")
+
+	parser.SetBlocklist("id.orig_h", nil)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"10.1.20.227", "tcp"}, {"10.1.20.229", "tcp"}}, "expected a removed blocklist to no longer apply")
+
+	parser.SetBlocklist("service", []string{"dns"})
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected an error for a field that isn't parsed")
+}
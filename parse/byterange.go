@@ -0,0 +1,18 @@
+package parse
+
+import "math"
+
+// SetByteRange limits parsing to the entries starting at a byte offset in
+// [start, end) of the Bro log, so that workers can each parse a shard of a
+// single large log. The header is still read from the start of the log.
+// An entry crossing a boundary belongs to the range it starts in, so
+// adjacent ranges parse every entry exactly once. An end of 0 or less
+// means the end of the log. Line numbers count from the start of the range,
+// and compressed logs can't be parsed by range.
+func (p *Parser) SetByteRange(start, end int64) {
+	if end <= 0 {
+		end = math.MaxInt64
+	}
+	p.rangeStart = start
+	p.rangeEnd = end
+}
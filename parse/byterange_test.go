@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetByteRange(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("../sample_logs/unordered.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"unordered.log": {Data: data}}
+
+	parser, err := NewParserFS(fsys, "unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	all, err := parser.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every split of the log into two shards parses every entry exactly once
+	for split := int64(1); split < int64(len(data)); split++ {
+		parser.SetByteRange(0, split)
+		first, err := parser.ReadAll()
+		assert.Nil(err)
+
+		parser.SetByteRange(split, 0)
+		second, err := parser.ReadAll()
+		assert.Nil(err)
+
+		assert.Equal(append(first, second...), all, "shards split at byte "+strconv.FormatInt(split, 10)+" are incorrect")
+	}
+
+	parser.SetByteRange(int64(len(data)), 0)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Nil(rows, "expected no entries past the end of the log")
+}
@@ -0,0 +1,384 @@
+package parse
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// bzip2 compresses a stream as independent blocks, each starting with a
+// 48 bit magic number that isn't byte aligned. The blocks of a stream are
+// found by scanning for the magic numbers, and each block is rewritten as
+// a stream of its own, so that blocks can be decompressed in parallel. The
+// magic numbers can also happen to be in the compressed data of a block:
+// the end of a stream is only taken as such once the header of the next
+// stream or the end of the file follows it, and a block that can't be
+// decompressed is decompressed again together with the block after it.
+const (
+	bzip2BlockMagic = 0x314159265359
+	bzip2EndMagic   = 0x177245385090
+	bzip2MagicMask  = 1<<48 - 1
+)
+
+// SetDecompressionWorkers sets the number of bzip2 blocks of a .bz2 log
+// decompressed in parallel. The default, 0, uses a worker per CPU, and 1
+// decompresses the log sequentially.
+func (p *Parser) SetDecompressionWorkers(workers int) {
+	p.decompressWorkers = workers
+}
+
+// newBzip2Reader returns a reader of the decompressed stream r, which is
+// decompressed by workers in parallel.
+func newBzip2Reader(r io.ReadCloser, workers int) io.ReadCloser {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers == 1 {
+		return &bzip2ReadCloser{Reader: bzip2.NewReader(r), compressed: r}
+	}
+
+	return newParallelBzip2Reader(r, workers, func(emit func(byte, bzip2Segment) error) error {
+		return splitBzip2(r, emit)
+	})
+}
+
+// newParallelBzip2Reader returns a reader of the blocks emitted by split,
+// decompressed by workers in parallel. r is closed with the reader.
+func newParallelBzip2Reader(r io.ReadCloser, workers int, split func(emit func(byte, bzip2Segment) error) error) *parallelBzip2Reader {
+
+	b := &parallelBzip2Reader{
+		blocks:     make(chan chan bzip2Block, workers*2),
+		done:       make(chan struct{}),
+		compressed: r,
+	}
+	jobs := make(chan bzip2Job)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				job.block <- job.decompress()
+			}
+		}()
+	}
+	go b.split(jobs, split)
+	return b
+}
+
+// bzip2ReadCloser closes the compressed stream of a sequential bzip2 reader.
+type bzip2ReadCloser struct {
+	io.Reader
+	compressed io.Closer
+}
+
+func (b *bzip2ReadCloser) Close() error {
+	return b.compressed.Close()
+}
+
+// bzip2Segment holds the bits from, to of data, the compressed bits of a
+// block between two magic numbers.
+type bzip2Segment struct {
+	data     []byte
+	from, to int64
+}
+
+// bzip2Job is a block, of one or more segments, to be rewritten as a stream
+// and decompressed into block.
+type bzip2Job struct {
+	level    byte
+	segments []bzip2Segment
+	block    chan bzip2Block
+}
+
+// decompress decompresses the block of the job.
+func (j bzip2Job) decompress() bzip2Block {
+	stream, err := newBzip2Stream(j.level, j.segments)
+	if err != nil {
+		return bzip2Block{err: err, job: j}
+	}
+	data, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(stream)))
+	return bzip2Block{data: data, err: err, job: j}
+}
+
+// bzip2Block is a decompressed block, along with the job it is of.
+type bzip2Block struct {
+	data []byte
+	err  error
+	job  bzip2Job
+}
+
+// parallelBzip2Reader reads the blocks of a bzip2 stream in order, while
+// they are decompressed in parallel.
+type parallelBzip2Reader struct {
+	blocks     chan chan bzip2Block
+	current    []byte
+	err        error
+	done       chan struct{}
+	closeOnce  sync.Once
+	compressed io.ReadCloser
+}
+
+// errBzip2Closed stops splitting the blocks of a closed reader.
+var errBzip2Closed = errors.New("Bzip2 reader is closed")
+
+// split hands the blocks emitted by split to the workers, queueing each
+// block for reading in the order of the stream.
+func (b *parallelBzip2Reader) split(jobs chan<- bzip2Job, split func(emit func(byte, bzip2Segment) error) error) {
+	defer close(b.blocks)
+	defer close(jobs)
+
+	err := split(func(level byte, segment bzip2Segment) error {
+		block := make(chan bzip2Block, 1)
+		select {
+		case b.blocks <- block:
+		case <-b.done:
+			return errBzip2Closed
+		}
+		select {
+		case jobs <- bzip2Job{level: level, segments: []bzip2Segment{segment}, block: block}:
+		case <-b.done:
+			return errBzip2Closed
+		}
+		return nil
+	})
+	if err != nil && err != errBzip2Closed {
+		block := make(chan bzip2Block, 1)
+		block <- bzip2Block{err: err}
+		select {
+		case b.blocks <- block:
+		case <-b.done:
+		}
+	}
+}
+
+func (b *parallelBzip2Reader) Read(buf []byte) (int, error) {
+	for len(b.current) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		next, ok := <-b.blocks
+		if !ok {
+			b.err = io.EOF
+			continue
+		}
+		block := b.merge(<-next)
+		b.current, b.err = block.data, block.err
+	}
+	n := copy(buf, b.current)
+	b.current = b.current[n:]
+	return n, nil
+}
+
+// merge decompresses a block that couldn't be decompressed again together
+// with the blocks after it, for as long as it can't, since it may have been
+// split at a magic number in its compressed data.
+func (b *parallelBzip2Reader) merge(block bzip2Block) bzip2Block {
+	for block.err != nil && block.job.segments != nil {
+		next, ok := <-b.blocks
+		if !ok {
+			return block
+		}
+		following := <-next
+		if following.job.segments == nil {
+			return block
+		}
+
+		merged := block.job
+		merged.segments = append(append([]bzip2Segment(nil), merged.segments...), following.job.segments...)
+		block = merged.decompress()
+	}
+	return block
+}
+
+func (b *parallelBzip2Reader) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	return b.compressed.Close()
+}
+
+// splitBzip2 calls emit with the level and the compressed bits of every
+// block of the bzip2 stream r, in order. Concatenated streams, ex: from
+// pbzip2, are split too.
+func splitBzip2(r io.Reader, emit func(level byte, segment bzip2Segment) error) error {
+
+	var (
+		buf        []byte
+		bufStart   int64 // bit offset of buf in the stream
+		level      byte
+		blockStart int64 = -1
+		endAt      int64 = -1 // bit offset of a stream end not yet followed by a header
+		skipUntil  int64      // bits of stream trailers and headers
+		headerAt   int64      // byte offset of the next stream header
+		window     uint64
+		read       int64 // bytes read
+		chunk      = make([]byte, 64*1024)
+	)
+
+	emitBlock := func(end int64) error {
+		from := blockStart - bufStart
+		to := end - bufStart
+		first := from / 8
+		segment := bzip2Segment{
+			data: append([]byte(nil), buf[first:(to+7)/8]...),
+			from: from - first*8,
+			to:   to - first*8,
+		}
+
+		// Only the bits from the start of the byte of the end are needed
+		drop := to / 8
+		buf = append(buf[:0], buf[drop:]...)
+		bufStart += drop * 8
+		return emit(level, segment)
+	}
+
+	for {
+		n, err := r.Read(chunk)
+		for _, c := range chunk[:n] {
+			buf = append(buf, c)
+			window = window<<8 | uint64(c)
+			read++
+
+			if read == headerAt+4 {
+				header := buf[len(buf)-4:]
+				valid := header[0] == 'B' && header[1] == 'Z' && header[2] == 'h' && header[3] >= '1' && header[3] <= '9'
+				switch {
+				case valid && endAt >= 0:
+					if err := emitBlock(endAt); err != nil {
+						return err
+					}
+					blockStart, endAt = -1, -1
+					level = header[3]
+				case valid:
+					level = header[3]
+				case endAt >= 0:
+					// The end magic number was compressed data of the block
+					endAt = -1
+				default:
+					return errors.New("Invalid bzip2 header")
+				}
+			}
+
+			// Check every bit alignment of a magic number ending in this byte
+			for shift := 7; shift >= 0; shift-- {
+				start := read*8 - int64(shift) - 48
+				if start < skipUntil {
+					continue
+				}
+				switch (window >> uint(shift)) & bzip2MagicMask {
+				case bzip2BlockMagic:
+					if blockStart >= 0 {
+						if err := emitBlock(start); err != nil {
+							return err
+						}
+					}
+					blockStart = start
+				case bzip2EndMagic:
+					if blockStart >= 0 {
+						endAt = start
+					}
+
+					// A stream ends with its checksum, padded to a byte
+					headerAt = (start + 48 + 32 + 7) / 8
+					skipUntil = (headerAt + 4) * 8
+				}
+			}
+
+			// Outside of a block, only the bytes of a magic number are kept
+			if blockStart < 0 && len(buf) > 8 {
+				drop := int64(len(buf) - 8)
+				buf = append(buf[:0], buf[drop:]...)
+				bufStart += drop * 8
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if read < 4 {
+		return errors.New("Invalid bzip2 header")
+	}
+	if endAt >= 0 {
+		return emitBlock(endAt)
+	}
+	if blockStart >= 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// newBzip2Stream returns a bzip2 stream of a single block, of the bits of
+// segments, with the checksum of the block as its own.
+func newBzip2Stream(level byte, segments []bzip2Segment) ([]byte, error) {
+
+	var bits int64
+	for _, segment := range segments {
+		bits += segment.to - segment.from
+	}
+	if bits < 80 {
+		return nil, errors.New("Bzip2 block is truncated")
+	}
+
+	var w bitWriter
+	w.out = make([]byte, 0, bits/8+16)
+	w.out = append(w.out, 'B', 'Z', 'h', level)
+
+	for _, segment := range segments {
+		pos := segment.from
+		for ; pos+8 <= segment.to; pos += 8 {
+			w.writeBits(readBits(segment.data, pos, 8), 8)
+		}
+		if pos < segment.to {
+			w.writeBits(readBits(segment.data, pos, uint(segment.to-pos)), uint(segment.to-pos))
+		}
+	}
+
+	// The checksum of a stream of a single block is that of the block,
+	// which follows its magic number
+	crc := readBits(w.out, 32+48, 32)
+	w.writeBits(bzip2EndMagic, 48)
+	w.writeBits(crc, 32)
+	return w.flush(), nil
+}
+
+// readBits returns the n bits, up to 56, at the bit offset pos of buf.
+func readBits(buf []byte, pos int64, n uint) uint64 {
+	var v uint64
+	first := pos / 8
+	last := (pos + int64(n) - 1) / 8
+	for i := first; i <= last; i++ {
+		v = v<<8 | uint64(buf[i])
+	}
+	trailing := uint((last+1)*8 - (pos + int64(n)))
+	return (v >> trailing) & (1<<n - 1)
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first.
+type bitWriter struct {
+	out   []byte
+	acc   uint64
+	nbits uint
+}
+
+// writeBits writes the low n bits, up to 56, of v.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	w.acc = w.acc<<n | v&(1<<n-1)
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.out = append(w.out, byte(w.acc>>w.nbits))
+	}
+}
+
+// flush pads the written bits to a byte and returns them.
+func (w *bitWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.out = append(w.out, byte(w.acc<<(8-w.nbits)))
+		w.nbits = 0
+	}
+	return w.out
+}
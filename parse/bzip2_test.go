@@ -0,0 +1,108 @@
+package parse
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBzip2(t *testing.T) {
+	assert := assert.New(t)
+
+	// Two concatenated streams of two blocks and one block
+	compressed, err := os.ReadFile("../sample_logs/conn.log.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := 0
+	err = splitBzip2(bytes.NewReader(compressed), func(level byte, segment bzip2Segment) error {
+		blocks++
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(blocks, 3, "split the streams into blocks incorrectly")
+
+	for _, workers := range []int{1, 2, 8} {
+		decompressed, err := io.ReadAll(newBzip2Reader(io.NopCloser(bytes.NewReader(compressed)), workers))
+		assert.Nil(err)
+		assert.True(bytes.Equal(decompressed, expected), "decompressed the log incorrectly")
+	}
+
+	parser, err := NewParser("../sample_logs/conn.log.bz2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetDecompressionWorkers(4)
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto"}, "read the header incorrectly")
+
+	parser.SetFields(fields)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 3000, "expected the rows of every block")
+	assert.Equal(rows[2999][0], "1452686403.408400", "read the last row incorrectly")
+
+	_, err = io.ReadAll(newBzip2Reader(io.NopCloser(bytes.NewReader(compressed[:len(compressed)/2])), 4))
+	assert.NotNil(err, "expected an error for a truncated log")
+}
+
+func TestBzip2FalseMagic(t *testing.T) {
+	assert := assert.New(t)
+
+	// An end magic number in the data of a block isn't followed by a header
+	var w bitWriter
+	w.out = []byte("BZh9")
+	w.writeBits(bzip2BlockMagic, 48)
+	w.writeBits(0x12345678, 32)
+	w.writeBits(0xabcd, 16)
+	w.writeBits(bzip2EndMagic, 48)
+	w.writeBits(0xdeadbeef, 32)
+	w.writeBits(0xaabbccddee, 40)
+	w.writeBits(bzip2EndMagic, 48)
+	w.writeBits(0x12345678, 32)
+
+	var segments []bzip2Segment
+	err := splitBzip2(bytes.NewReader(w.flush()), func(level byte, segment bzip2Segment) error {
+		segments = append(segments, segment)
+		return nil
+	})
+	assert.Nil(err)
+	if assert.Equal(len(segments), 1, "expected the end magic number in the block to be skipped") {
+		assert.Equal(segments[0].to-segments[0].from, int64(48+32+16+48+32+40), "expected the block to end at the end of the stream")
+	}
+
+	// Blocks split at a magic number in their data are merged again
+	compressed, err := os.ReadFile("../sample_logs/conn.log.bz2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := io.ReadAll(bzip2.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := newParallelBzip2Reader(io.NopCloser(bytes.NewReader(compressed)), 4, func(emit func(byte, bzip2Segment) error) error {
+		return splitBzip2(bytes.NewReader(compressed), func(level byte, segment bzip2Segment) error {
+			middle := (segment.from + segment.to) / 2
+			if err := emit(level, bzip2Segment{data: segment.data, from: segment.from, to: middle}); err != nil {
+				return err
+			}
+			return emit(level, bzip2Segment{data: segment.data, from: middle, to: segment.to})
+		})
+	})
+	decompressed, err := io.ReadAll(reader)
+	assert.Nil(err)
+	assert.True(bytes.Equal(decompressed, expected), "decompressed the split blocks incorrectly")
+}
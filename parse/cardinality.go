@@ -0,0 +1,115 @@
+package parse
+
+import "strconv"
+
+// SetMaxKeys bounds the number of distinct keys in-memory operations hold at
+// once. Once the bound is exceeded the operation switches to an approximation
+// and a *MaxKeysError is reported: Cardinality counts with HyperLogLog,
+// Sessionize ends the session idle for longest, TimeSeries doubles its
+// window and Partition routes the rows of new keys to PartitionOverflowKey.
+// SortBy and ReadAll, which hold rows rather than keys, can't approximate
+// and end with the *MaxKeysError. A bound of 0, the default, means no bound.
+func (p *Parser) SetMaxKeys(maxKeys int) {
+	p.maxKeys = maxKeys
+}
+
+// MaxKeysError reports that an in-memory operation exceeded the bound set
+// by SetMaxKeys, and that its result is an approximation, or that it was
+// stopped when it can't approximate.
+type MaxKeysError struct {
+	Operation string
+	MaxKeys   int
+	Stopped   bool
+}
+
+func (e *MaxKeysError) Error() string {
+	if e.Stopped {
+		return e.Operation + " exceeded " + strconv.Itoa(e.MaxKeys) + " keys and was stopped"
+	}
+	return e.Operation + " exceeded " + strconv.Itoa(e.MaxKeys) + " keys, its result is approximate"
+}
+
+// exceedsMaxKeys reports whether holding keys distinct keys is over the bound.
+func (p *Parser) exceedsMaxKeys(keys int) bool {
+	return p.maxKeys > 0 && keys > p.maxKeys
+}
+
+// Cardinality counts the exact number of distinct values of a field
+// in the Bro log. Every distinct value is held in memory, so for fields
+// with millions of unique values ApproxCardinality should be used instead.
+// When SetMaxKeys is set and exceeded, counting continues with HyperLogLog
+// and the estimate is returned instead.
+func (p *Parser) Cardinality(field string) (int, error) {
+
+	seen := make(map[string]struct{})
+	var hll *hyperLogLog
+
+	err := p.eachFieldValue(field, func(value string) {
+		if hll != nil {
+			hll.add(value)
+			return
+		}
+
+		seen[value] = struct{}{}
+
+		if p.exceedsMaxKeys(len(seen)) {
+			hll = newHyperLogLog()
+			for key := range seen {
+				hll.add(key)
+			}
+			seen = nil
+			p.reportError(&MaxKeysError{Operation: "Cardinality of " + field, MaxKeys: p.maxKeys})
+		}
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	if hll != nil {
+		return hll.count(), nil
+	}
+
+	return len(seen), nil
+}
+
+// ApproxCardinality estimates the number of distinct values of a field
+// in the Bro log using HyperLogLog. It uses a fixed 16KB of memory no matter
+// how many unique values there are, at the cost of a standard error of
+// about 0.81% in the returned count.
+func (p *Parser) ApproxCardinality(field string) (int, error) {
+
+	hll := newHyperLogLog()
+
+	err := p.eachFieldValue(field, hll.add)
+	if err != nil {
+		return -1, err
+	}
+
+	return hll.count(), nil
+}
+
+// eachFieldValue calls fn with the value of field for every entry
+// in the Bro log. Values of addr fields are canonicalized, so that an
+// address is counted once however it is written.
+func (p *Parser) eachFieldValue(field string, fn func(string)) error {
+
+	allFields, err := p.logFields()
+	if err != nil {
+		return err
+	}
+
+	index, err := getIndex(allFields, field)
+	if err != nil {
+		return err
+	}
+
+	_, types, _ := p.Schema()
+	isAddr := index < len(types) && types[index] == "addr"
+
+	return p.eachEntry(func(lineNum int, entry []string) error {
+		if index < len(entry) {
+			fn(groupKey(isAddr, entry[index]))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,174 @@
+package parse
+
+import (
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinality(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := parser.Cardinality("id.orig_h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(count, 1, "counted distinct values incorrectly")
+
+	approx, err := parser.ApproxCardinality("id.orig_h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(approx, 1, "estimated distinct values incorrectly")
+
+	_, err = parser.Cardinality("not_a_field")
+	assert.NotNil(err, "expected an error for an unknown field")
+}
+
+func TestCardinalityCanonicalAddrs(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tproto\n" +
+		"#types\taddr\tenum\n" +
+		"1.2.3.4\ttcp\n" +
+		"::ffff:1.2.3.4\ttcp\n" +
+		"2001:db8::1\tudp\n" +
+		"2001:DB8:0:0::1\tudp\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := parser.Cardinality("id.orig_h")
+	assert.Nil(err)
+	assert.Equal(count, 2, "expected an address to be counted once however it is written")
+}
+
+func TestHyperLogLogAccuracy(t *testing.T) {
+	assert := assert.New(t)
+
+	hll := newHyperLogLog()
+	distinct := 100000
+	for i := 0; i < distinct; i++ {
+		hll.add("10.0." + strconv.Itoa(i))
+		hll.add("10.0." + strconv.Itoa(i))
+	}
+
+	estimate := hll.count()
+	errorRate := float64(estimate-distinct) / float64(distinct)
+	assert.InDelta(errorRate, 0, 0.03, "estimate is outside of the expected error")
+}
+
+func TestCardinalityMaxKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetMaxKeys(2)
+	parser.CreateErrorBuffer(10)
+
+	count, err := parser.Cardinality("uid")
+	assert.Nil(err)
+	assert.Equal(count, 4, "approximate count should still be close for few values")
+
+	if assert.Equal(len(parser.Errors), 1, "exceeding the bound should be reported") {
+		_, ok := (<-parser.Errors).(*MaxKeysError)
+		assert.True(ok, "expected a *MaxKeysError")
+	}
+
+	count, err = parser.Cardinality("proto")
+	assert.Nil(err)
+	assert.Equal(count, 3, "counted distinct values incorrectly")
+	assert.Equal(len(parser.Errors), 1, "exceeding the bound for proto should be reported")
+}
+
+func TestMaxKeysOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	isMaxKeysError := func(err error, stopped bool) bool {
+		maxKeysErr, ok := err.(*MaxKeysError)
+		return ok && maxKeysErr.Stopped == stopped
+	}
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetMaxKeys(2)
+
+	_, err = parser.ReadAll()
+	assert.True(isMaxKeysError(err, true), "expected ReadAll to stop past the bound")
+
+	_, err = parser.SortBy("ts", true)
+	assert.True(isMaxKeysError(err, true), "expected SortBy to stop past the bound")
+
+	parser.CreateErrorBuffer(10)
+	var keys []string
+	err = parser.Partition("proto", func(key string, row []string) {
+		keys = append(keys, key)
+	})
+	assert.Nil(err)
+	assert.Equal(keys, []string{"tcp", "udp", "tcp", PartitionOverflowKey}, "expected new keys past the bound to overflow")
+	if assert.Equal(len(parser.Errors), 1, "exceeding the bound should be reported once") {
+		assert.True(isMaxKeysError(<-parser.Errors, false), "expected an approximate *MaxKeysError")
+	}
+
+	times, values, err := parser.TimeSeries("ts", time.Second)
+	assert.Nil(err)
+	assert.Equal(len(times), 2, "expected the window to grow until the buckets are within the bound")
+	assert.InDelta(values[0]+values[1], 4*1452684905.0, 1, "expected every value to be summed")
+	assert.Equal(len(parser.Errors), 1, "exceeding the bound should be reported once")
+	<-parser.Errors
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\n" +
+		"#types\ttime\taddr\n" +
+		"1452684900.000000\t10.1.20.227\n" +
+		"1452684901.000000\t10.1.20.228\n" +
+		"1452684902.000000\t10.1.20.227\n" +
+		"1452684903.000000\t10.1.20.229\n" +
+		"1452684904.000000\t10.1.20.228\n")}}
+
+	parser, err = NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err = parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetMaxKeys(2)
+	parser.CreateErrorBuffer(10)
+
+	var hosts []string
+	for session := range parser.Sessionize("id.orig_h", time.Minute) {
+		hosts = append(hosts, session.Host)
+	}
+	assert.Equal(hosts, []string{"10.1.20.228", "10.1.20.227", "10.1.20.229", "10.1.20.228"}, "expected the session idle for longest to end past the bound")
+	var errs []error
+	for err := range parser.Errors {
+		errs = append(errs, err)
+	}
+	if assert.Equal(len(errs), 1, "exceeding the bound should be reported once") {
+		assert.True(isMaxKeysError(errs[0], false), "expected an approximate *MaxKeysError")
+	}
+}
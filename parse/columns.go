@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ColumnCountError reports an entry with more values than the fields of
+// its header, usually because a value holds the separator, ex: a JSON
+// blob with tabs in a string field. Such entries are skipped when parsing
+// all fields, and their values after the value holding the separator don't
+// line up with specific fields.
+type ColumnCountError struct {
+	Line     int
+	Expected int
+	Got      int
+}
+
+func (e *ColumnCountError) Error() string {
+	return "Entry on line " + strconv.Itoa(e.Line) + " has " + strconv.Itoa(e.Got) +
+		" values but there are " + strconv.Itoa(e.Expected) + " fields"
+}
+
+// SetReassembleJSON enables or disables putting back together JSON values
+// split by the separator, ex: a JSON blob with tabs embedded by a Zeek
+// script in a string field. In an entry with more values than fields, a
+// string value starting with { or [ is joined with the values after it
+// until it is valid JSON, as long as there are extra values. Entries that
+// still have too many values are reported with a ColumnCountError, as they
+// are without it.
+func (p *Parser) SetReassembleJSON(enabled bool) {
+	p.reassembleJSON = enabled
+}
+
+// checkColumns returns an entry with the values split from JSON values
+// joined back when enabled, and reports entries with extra values.
+func (p *Parser) checkColumns(h *header, lineNum int, entry []string) []string {
+
+	if h.fields == nil || len(entry) <= len(h.fields) {
+		return entry
+	}
+
+	if p.reassembleJSON {
+		entry = reassembleJSON(entry, h.types, len(entry)-len(h.fields), p.separator)
+	}
+
+	if len(entry) > len(h.fields) {
+		p.reportError(&ColumnCountError{Line: lineNum, Expected: len(h.fields), Got: len(entry)})
+	}
+	return entry
+}
+
+// reassembleJSON joins the values split from JSON values of entry, which
+// has extra values.
+func reassembleJSON(entry, types []string, extra int, separator string) []string {
+
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	reassembled := make([]string, 0, len(entry)-extra)
+	for i := 0; i < len(entry); i++ {
+		value := entry[i]
+		field := len(reassembled)
+		isString := types == nil || (field < len(types) && types[field] == "string")
+
+		if extra > 0 && isString && (strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")) && !json.Valid([]byte(value)) {
+			for pieces := 1; pieces <= extra && i+pieces < len(entry); pieces++ {
+				// A tab is invalid in a JSON string, the separator is checked as a space
+				split := entry[i : i+pieces+1]
+				if json.Valid([]byte(strings.Join(split, " "))) {
+					value = strings.Join(split, separator)
+					i += pieces
+					extra -= pieces
+					break
+				}
+			}
+		}
+
+		reassembled = append(reassembled, value)
+	}
+
+	return reassembled
+}
@@ -0,0 +1,45 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetReassembleJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"notice.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tmsg\tsub\n" +
+		"#types\ttime\tstring\tstring\n" +
+		"1300475167.096535\t{\"a\":\"x\ty\",\"b\":[1,\t2]}\tdone\n" +
+		"1300475168.096535\tplain\ttext\textra\n")}}
+
+	parser, err := NewParserFS(fsys, "notice.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	parser.CreateErrorBuffer(10)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 0, "expected entries with extra values skipped without reassembling")
+	assert.Equal((<-parser.Errors).(*ColumnCountError).Line, 4, "expected the entry with extra values reported")
+	<-parser.Errors
+
+	parser.SetReassembleJSON(true)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 1, "expected the entry with JSON kept")
+	assert.Equal(rows[0], []string{"1300475167.096535", "{\"a\":\"x\ty\",\"b\":[1,\t2]}", "done"}, "expected the JSON value put back together")
+
+	columnErr := (<-parser.Errors).(*ColumnCountError)
+	assert.Equal(columnErr.Line, 5, "expected the entry without JSON still reported")
+	assert.Equal(columnErr.Error(), "Entry on line 5 has 4 values but there are 3 fields", "error message is incorrect")
+}
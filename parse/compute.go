@@ -0,0 +1,303 @@
+package parse
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// computedField is a column computed from the other fields of a row.
+type computedField struct {
+	name string
+	expr expression
+}
+
+// AddComputedField appends a column to every row, computed by an arithmetic
+// expression over the fields of the row, ex: "orig_bytes + resp_bytes".
+// Expressions support + - * / %, parentheses, numbers and the names of the
+// fields parsed, valued by their typed value: intervals are in seconds and
+// times in seconds since the epoch. The column is unset when a field it
+// uses is, or when the result is not a number (ex: a division by 0).
+// Computed columns follow the fields of the row in the order they are added.
+func (p *Parser) AddComputedField(name, expr string) error {
+
+	if p.fields == nil {
+		return errors.New("No fields parsed")
+	}
+
+	parsed, err := parseExpression(expr)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range parsed.fields(nil) {
+		if _, err := getIndex(p.fields, field); err != nil {
+			return err
+		}
+	}
+
+	p.computedFields = append(p.computedFields, computedField{name: name, expr: parsed})
+	return nil
+}
+
+// computeFields returns the values of the computed fields for a row.
+func (p *Parser) computeFields(row []string) ([]string, error) {
+
+	types, err := p.rowTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	value := func(field string) (float64, bool, error) {
+		index, err := getIndex(p.fields, field)
+		if err != nil || index >= len(row) || index >= len(types) {
+			return 0, false, errors.New("Row is missing field: " + field)
+		}
+
+		typed, err := p.coerce(types[index], row[index])
+		if err != nil {
+			return 0, false, err
+		}
+		return numericValue(field, typed)
+	}
+
+	values := make([]string, len(p.computedFields))
+	for i, computed := range p.computedFields {
+		result, ok, err := computed.expr.eval(value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || math.IsNaN(result) || math.IsInf(result, 0) {
+			values[i] = p.markers.Unset
+			continue
+		}
+		values[i] = strconv.FormatFloat(result, 'f', -1, 64)
+	}
+
+	return values, nil
+}
+
+// numericValue converts a typed value to a number. The boolean is false for
+// unset values.
+func numericValue(field string, typed interface{}) (float64, bool, error) {
+	switch v := typed.(type) {
+	case nil:
+		return 0, false, nil
+	case uint64:
+		return float64(v), true, nil
+	case int64:
+		return float64(v), true, nil
+	case float64:
+		return v, true, nil
+	case uint16:
+		return float64(v), true, nil
+	case time.Duration:
+		return v.Seconds(), true, nil
+	case time.Time:
+		return float64(v.UnixNano()) / 1e9, true, nil
+	}
+	return 0, false, errors.New("Field is not a number: " + field)
+}
+
+// expression is a parsed arithmetic expression. eval reports false when a
+// field it uses is unset.
+type expression interface {
+	eval(value func(field string) (float64, bool, error)) (float64, bool, error)
+	fields(fields []string) []string
+}
+
+type numberExpr float64
+
+func (n numberExpr) eval(value func(string) (float64, bool, error)) (float64, bool, error) {
+	return float64(n), true, nil
+}
+
+func (n numberExpr) fields(fields []string) []string { return fields }
+
+type fieldExpr string
+
+func (f fieldExpr) eval(value func(string) (float64, bool, error)) (float64, bool, error) {
+	return value(string(f))
+}
+
+func (f fieldExpr) fields(fields []string) []string { return append(fields, string(f)) }
+
+type negateExpr struct{ operand expression }
+
+func (n negateExpr) eval(value func(string) (float64, bool, error)) (float64, bool, error) {
+	v, ok, err := n.operand.eval(value)
+	return -v, ok, err
+}
+
+func (n negateExpr) fields(fields []string) []string { return n.operand.fields(fields) }
+
+type binaryExpr struct {
+	op          byte
+	left, right expression
+}
+
+func (b binaryExpr) eval(value func(string) (float64, bool, error)) (float64, bool, error) {
+
+	left, ok, err := b.left.eval(value)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	right, ok, err := b.right.eval(value)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+
+	switch b.op {
+	case '+':
+		return left + right, true, nil
+	case '-':
+		return left - right, true, nil
+	case '*':
+		return left * right, true, nil
+	case '/':
+		return left / right, true, nil
+	}
+	return math.Mod(left, right), true, nil
+}
+
+func (b binaryExpr) fields(fields []string) []string {
+	return b.right.fields(b.left.fields(fields))
+}
+
+// expressionParser is a recursive descent parser of arithmetic expressions.
+type expressionParser struct {
+	expr string
+	pos  int
+}
+
+func parseExpression(expr string) (expression, error) {
+
+	parser := &expressionParser{expr: expr}
+	parsed, err := parser.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	parser.skipSpaces()
+	if parser.pos < len(expr) {
+		return nil, errors.New("Unexpected " + expr[parser.pos:] + " in expression: " + expr)
+	}
+
+	return parsed, nil
+}
+
+func (e *expressionParser) skipSpaces() {
+	for e.pos < len(e.expr) && e.expr[e.pos] == ' ' {
+		e.pos++
+	}
+}
+
+// peek returns the next character, or 0 at the end of the expression.
+func (e *expressionParser) peek() byte {
+	e.skipSpaces()
+	if e.pos < len(e.expr) {
+		return e.expr[e.pos]
+	}
+	return 0
+}
+
+// sum parses terms separated by + and -.
+func (e *expressionParser) sum() (expression, error) {
+
+	left, err := e.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for op := e.peek(); op == '+' || op == '-'; op = e.peek() {
+		e.pos++
+		right, err := e.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// term parses factors separated by *, / and %.
+func (e *expressionParser) term() (expression, error) {
+
+	left, err := e.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for op := e.peek(); op == '*' || op == '/' || op == '%'; op = e.peek() {
+		e.pos++
+		right, err := e.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// factor parses a number, a field, a negation or a parenthesized sum.
+func (e *expressionParser) factor() (expression, error) {
+
+	c := e.peek()
+	switch {
+	case c == '(':
+		e.pos++
+		inner, err := e.sum()
+		if err != nil {
+			return nil, err
+		}
+		if e.peek() != ')' {
+			return nil, errors.New("Missing ) in expression: " + e.expr)
+		}
+		e.pos++
+		return inner, nil
+
+	case c == '-':
+		e.pos++
+		operand, err := e.factor()
+		if err != nil {
+			return nil, err
+		}
+		return negateExpr{operand}, nil
+
+	case c >= '0' && c <= '9' || c == '.':
+		start := e.pos
+		for e.pos < len(e.expr) && (e.expr[e.pos] >= '0' && e.expr[e.pos] <= '9' || e.expr[e.pos] == '.') {
+			e.pos++
+		}
+		number, err := strconv.ParseFloat(e.expr[start:e.pos], 64)
+		if err != nil {
+			return nil, errors.New("Invalid number in expression: " + e.expr[start:e.pos])
+		}
+		return numberExpr(number), nil
+
+	case isFieldNameStart(c):
+		start := e.pos
+		for e.pos < len(e.expr) && isFieldNameByte(e.expr[e.pos]) {
+			e.pos++
+		}
+		return fieldExpr(e.expr[start:e.pos]), nil
+	}
+
+	if c == 0 {
+		return nil, errors.New("Unexpected end of expression: " + e.expr)
+	}
+	return nil, errors.New("Unexpected " + string(c) + " in expression: " + e.expr)
+}
+
+// isFieldNameStart reports whether c can start a field name.
+func isFieldNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// isFieldNameByte reports whether c can be in a field name, ex: id.orig_p.
+func isFieldNameByte(c byte) bool {
+	return isFieldNameStart(c) || c >= '0' && c <= '9' || c == '.'
+}
@@ -0,0 +1,38 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddComputedField(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\tduration\torig_bytes\tresp_bytes\n" +
+		"#types\tstring\tinterval\tcount\tcount\n" +
+		"CbOiIv2wbbH7F25W21\t2.000000\t100\t1000\n" +
+		"C7fIlMZDuRiqjpYbb\t0.000000\t-\t50\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "duration", "orig_bytes", "resp_bytes"})
+
+	assert.Nil(parser.AddComputedField("total_bytes", "orig_bytes + resp_bytes"))
+	assert.Nil(parser.AddComputedField("rate", "(orig_bytes+resp_bytes) / duration"))
+	assert.Nil(parser.AddComputedField("resp_kb", "-resp_bytes * -2 % 3 + 0.5"))
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0][4:], []string{"1100", "550", "2.5"}, "computed fields incorrectly")
+	assert.Equal(rows[1][4:], []string{"-", "-", "1.5"}, "unset fields and divisions by 0 should be unset")
+
+	assert.NotNil(parser.AddComputedField("bad", "orig_bytes +"), "expected an error for an incomplete expression")
+	assert.NotNil(parser.AddComputedField("bad", "(orig_bytes"), "expected an error for a missing )")
+	assert.NotNil(parser.AddComputedField("bad", "service * 2"), "expected an error for an unknown field")
+	assert.NotNil(parser.AddComputedField("bad", "orig_bytes $ 2"), "expected an error for an unknown operator")
+}
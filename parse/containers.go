@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+)
+
+// SetSortContainers enables or disables sorting the elements of set
+// fields, ex: set[string], in every row emitted. Sets are unordered, so
+// two rows holding the same sets can differ only by the order of their
+// elements; with sorted sets they are equal, which makes dedup, row IDs
+// and diffs reliable. Elements are sorted as strings. Vectors are ordered
+// and keep the order of the log.
+func (p *Parser) SetSortContainers(enabled bool) {
+	p.sortContainers = enabled
+}
+
+// resolveSetFields returns the indexes of the set fields in the fields
+// parsed, when their elements are sorted.
+func (p *Parser) resolveSetFields() ([]int, error) {
+
+	if !p.sortContainers {
+		return nil, nil
+	}
+
+	types, err := p.rowTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for i, broType := range types {
+		if strings.HasPrefix(broType, "set[") {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// sortSets returns a row with the elements of the sets at indexes, split
+// on separator, sorted.
+func sortSets(indexes []int, row []string, separator string) []string {
+
+	sorted, copied := row, false
+	for _, index := range indexes {
+		if index >= len(row) || !strings.Contains(row[index], separator) {
+			continue
+		}
+
+		elements := strings.Split(row[index], separator)
+		if sort.StringsAreSorted(elements) {
+			continue
+		}
+		sort.Strings(elements)
+
+		if !copied {
+			sorted, copied = append([]string{}, row...), true
+		}
+		sorted[index] = strings.Join(elements, separator)
+	}
+
+	return sorted
+}
@@ -0,0 +1,40 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSortContainers(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"dns.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tquery\ttunnel_parents\tanswers\n" +
+		"#types\tstring\tset[string]\tvector[string]\n" +
+		"example.com\tCb,Ca,Cc\t10.0.0.2,10.0.0.1\n" +
+		"example.org\t(empty)\t-\n")}}
+
+	parser, err := NewParserFS(fsys, "dns.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0][1], "Cb,Ca,Cc", "expected sets in the order of the log by default")
+
+	parser.SetSortContainers(true)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"example.com", "Ca,Cb,Cc", "10.0.0.2,10.0.0.1"},
+		{"example.org", "(empty)", "-"},
+	}, "expected sets sorted and vectors kept in order")
+}
@@ -0,0 +1,111 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The ways SetControlBytes handles values with control bytes.
+const (
+	// ControlStrip removes the control bytes from values.
+	ControlStrip = "strip"
+	// ControlEscape escapes control bytes as \xNN, the way Bro escapes
+	// non-printable bytes.
+	ControlEscape = "escape"
+	// ControlReport reports rows with control bytes as a ControlByteError,
+	// without emitting them.
+	ControlReport = "report"
+)
+
+// ControlByteError reports a value holding NUL or other control bytes.
+type ControlByteError struct {
+	Line  int
+	Field string
+	Value string
+}
+
+func (e *ControlByteError) Error() string {
+	return "Value of " + e.Field + " on line " + strconv.Itoa(e.Line) + " has control bytes: " + strconv.Quote(e.Value)
+}
+
+// SetControlBytes sets how values holding NUL or other control bytes
+// (0x00-0x1f and 0x7f), ex: from malformed or attacker influenced logs,
+// are handled before rows are emitted: ControlStrip, ControlEscape or
+// ControlReport. Tabs and the separator of the log aren't control bytes,
+// as values hold them once their escaped separator (ex: \x09) is read. By
+// default values are emitted as they are read.
+func (p *Parser) SetControlBytes(mode string) error {
+
+	switch mode {
+	case ControlStrip, ControlEscape, ControlReport:
+	default:
+		return errors.New("Unknown control bytes mode: " + mode)
+	}
+
+	p.controlBytes = mode
+	return nil
+}
+
+// handleControlBytes returns a row with its control bytes handled, and
+// false if it should not be emitted.
+func (p *Parser) handleControlBytes(lineNum int, row []string) ([]string, bool) {
+
+	if p.controlBytes == "" {
+		return row, true
+	}
+
+	var handled []string
+	for i, value := range row {
+		if strings.IndexFunc(value, p.isValueControlByte) < 0 {
+			continue
+		}
+
+		if p.controlBytes == ControlReport {
+			field := strconv.Itoa(i)
+			if i < len(p.fields) {
+				field = p.fields[i]
+			}
+			p.reportError(&ControlByteError{Line: lineNum, Field: field, Value: value})
+			return nil, false
+		}
+
+		if handled == nil {
+			handled = append([]string{}, row...)
+		}
+		handled[i] = replaceControlBytes(value, p.isValueControlByte, p.controlBytes == ControlEscape)
+	}
+
+	if handled == nil {
+		return row, true
+	}
+	return handled, true
+}
+
+// isControlByte reports whether r is an ASCII control character.
+func isControlByte(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// isValueControlByte reports whether r is a control character of a value,
+// which tabs and the bytes of the separator aren't.
+func (p *Parser) isValueControlByte(r rune) bool {
+	return isControlByte(r) && r != '\t' && !strings.ContainsRune(p.separator, r)
+}
+
+// replaceControlBytes strips or escapes the control bytes of a value.
+func replaceControlBytes(value string, isControl func(rune) bool, escape bool) string {
+
+	var replaced strings.Builder
+	for i := 0; i < len(value); i++ {
+		if !isControl(rune(value[i])) {
+			replaced.WriteByte(value[i])
+			continue
+		}
+		if escape {
+			fmt.Fprintf(&replaced, "\\x%02x", value[i])
+		}
+	}
+	return replaced.String()
+}
@@ -0,0 +1,62 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetControlBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"http.log": {Data: []byte(followHeader +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"1452684904.908400\tC7fIlMZD\x00uRiqjpYbb\t\x1b[31mtcp\n" +
+		"1452684905.908400\tCHhAvVGS1DHFjwGM9\tt\\x09cp\n")}}
+
+	parse := func(mode string) ([][]string, []error) {
+		parser, err := NewParserFS(fsys, "http.log", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Nil(parser.SetControlBytes(mode))
+		parser.SetFields([]string{"ts", "uid", "proto"})
+		parser.CreateErrorBuffer(10)
+
+		rows, err := parser.ReadAll()
+		assert.Nil(err)
+		close(parser.Errors)
+
+		var errs []error
+		for err := range parser.Errors {
+			errs = append(errs, err)
+		}
+		return rows, errs
+	}
+
+	rows, _ := parse(ControlStrip)
+	assert.Equal(rows[1], []string{"1452684904.908400", "C7fIlMZDuRiqjpYbb", "[31mtcp"}, "expected control bytes to be stripped")
+
+	rows, _ = parse(ControlEscape)
+	assert.Equal(rows[1], []string{"1452684904.908400", "C7fIlMZD\\x00uRiqjpYbb", "\\x1b[31mtcp"}, "expected control bytes to be escaped")
+	assert.Equal(rows[0], []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp"}, "values without control bytes should be kept")
+	assert.Equal(rows[2], []string{"1452684905.908400", "CHhAvVGS1DHFjwGM9", "t\tcp"}, "an escaped separator should not be a control byte")
+
+	rows, errs := parse(ControlReport)
+	assert.Equal(len(rows), 2, "rows with control bytes should not be emitted")
+	assert.Equal(rows[1][2], "t\tcp", "rows with an escaped separator should be emitted")
+	if assert.Equal(len(errs), 1, "expected the row to be reported") {
+		controlErr, ok := errs[0].(*ControlByteError)
+		assert.True(ok, "expected a ControlByteError")
+		assert.Equal(controlErr.Line, 5, "reported the wrong line")
+		assert.Equal(controlErr.Field, "uid", "reported the wrong field")
+	}
+
+	parser, err := NewParserFS(fsys, "http.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(parser.Probe(), "a short log with a NUL byte in a value should not be binary")
+	assert.NotNil(parser.SetControlBytes("drop"), "expected an error for an unknown mode")
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"compress/bzip2"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// CorruptTailError reports a compressed log that can't be decompressed
+// past some point, usually because it was cut short by an interrupted
+// write. Rows before the corruption are still parsed.
+type CorruptTailError struct {
+	Err error
+}
+
+func (e *CorruptTailError) Error() string {
+	return "Compressed log is corrupt, rows after the corruption are lost: " + e.Err.Error()
+}
+
+func (e *CorruptTailError) Unwrap() error {
+	return e.Err
+}
+
+// SetTolerateTailCorruption makes a compressed log that is truncated or
+// corrupt, ex: an archive cut short by an interrupted write, end at the
+// corruption instead of failing. The rows decompressed before it are
+// parsed, and a *CorruptTailError is reported. The line cut off by the
+// corruption is never parsed, and is reported as a *TruncatedError.
+func (p *Parser) SetTolerateTailCorruption(tolerate bool) {
+	p.tolerateTail = tolerate
+}
+
+// tailReader ends a decompressed stream at the first corruption found by
+// its decompressor, instead of returning the error.
+type tailReader struct {
+	r       io.Reader
+	corrupt func(error)
+	done    bool
+}
+
+func (t *tailReader) Read(b []byte) (int, error) {
+	if t.done {
+		return 0, io.EOF
+	}
+
+	n, err := t.r.Read(b)
+	if err != nil && isCorruption(err) {
+		t.done = true
+		t.corrupt(&CorruptTailError{Err: err})
+		return n, io.EOF
+	}
+	return n, err
+}
+
+// corrupted reports whether the stream ended at a corruption.
+func (t *tailReader) corrupted() bool {
+	return t != nil && t.done
+}
+
+// isCorruption reports whether err is a decompressor finding a truncated
+// or corrupt stream.
+func isCorruption(err error) bool {
+	var flateErr flate.CorruptInputError
+	var bzip2Err bzip2.StructuralError
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, gzip.ErrChecksum) ||
+		errors.Is(err, gzip.ErrHeader) ||
+		errors.As(err, &flateErr) ||
+		errors.As(err, &bzip2Err)
+}
@@ -0,0 +1,100 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTolerateTailCorruption(t *testing.T) {
+	assert := assert.New(t)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(followHeader))
+	for i := 0; i < 1000; i++ {
+		gz.Write([]byte("1452684903.908400\tC" + strconv.Itoa(i*7919) + "\ttcp\n"))
+	}
+	gz.Close()
+
+	// The archive is cut short in the middle of its data
+	truncated := compressed.Bytes()[:compressed.Len()/2]
+	fsys := fstest.MapFS{"conn.log.gz": {Data: truncated}}
+
+	parser, err := NewParserFS(fsys, "conn.log.gz", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetTolerateTailCorruption(true)
+	parser.CreateBuffer(1000)
+	parser.CreateErrorBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows int
+	for row := range parser.Row {
+		assert.Equal(row[1], "C"+strconv.Itoa(rows*7919), "parsed the rows before the corruption incorrectly")
+		rows++
+	}
+	assert.True(rows > 0 && rows < 1000, "expected the rows before the corruption")
+
+	var corrupt int
+	for err := range parser.Errors {
+		if _, ok := err.(*CorruptTailError); ok {
+			corrupt++
+		}
+	}
+	assert.Equal(corrupt, 1, "expected the corruption to be reported once")
+}
+
+func TestTailCorruptionCutsOffLastLine(t *testing.T) {
+	assert := assert.New(t)
+
+	// Stored blocks keep the lines in the clear, so that the archive can be
+	// cut inside any of them
+	var compressed bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&compressed, gzip.NoCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz.Write([]byte(followHeader))
+	for i := 0; i < 20; i++ {
+		gz.Write([]byte("1452684903.908400\tC" + strconv.Itoa(i) + "\ttcp\n"))
+	}
+	gz.Close()
+
+	for cut := compressed.Len() / 2; cut < compressed.Len()-8; cut++ {
+		fsys := fstest.MapFS{"conn.log.gz": {Data: compressed.Bytes()[:cut]}}
+
+		parser, err := NewParserFS(fsys, "conn.log.gz", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser.SetFields([]string{"ts", "uid", "proto"})
+		parser.SetTolerateTailCorruption(true)
+		parser.CreateBuffer(100)
+		parser.CreateErrorBuffer(10)
+
+		go parser.BufferRow()
+
+		var rows int
+		for row := range parser.Row {
+			assert.Equal(row, []string{"1452684903.908400", "C" + strconv.Itoa(rows), "tcp"}, "expected only complete rows before the corruption")
+			rows++
+		}
+		for err := range parser.Errors {
+			if truncated, ok := err.(*TruncatedError); ok {
+				assert.Equal(truncated.Line, rows+4, "reported the wrong line as cut off")
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+)
+
+// The SQL dialects GenerateDDL writes.
+const (
+	DialectPostgres   = "postgres"
+	DialectClickHouse = "clickhouse"
+	DialectSQLite     = "sqlite"
+)
+
+// sqlTypes maps Bro types to the column types of each dialect.
+var sqlTypes = map[string]map[string]string{
+	DialectPostgres: {
+		"bool":     "BOOLEAN",
+		"count":    "BIGINT",
+		"int":      "BIGINT",
+		"double":   "DOUBLE PRECISION",
+		"time":     "TIMESTAMPTZ",
+		"interval": "INTERVAL",
+		"port":     "INTEGER",
+		"addr":     "INET",
+		"subnet":   "CIDR",
+	},
+	DialectClickHouse: {
+		"bool":     "Bool",
+		"count":    "UInt64",
+		"int":      "Int64",
+		"double":   "Float64",
+		"time":     "DateTime64(6)",
+		"interval": "Float64",
+		"port":     "UInt16",
+		"enum":     "LowCardinality(String)",
+	},
+	DialectSQLite: {
+		"bool":     "INTEGER",
+		"count":    "INTEGER",
+		"int":      "INTEGER",
+		"double":   "REAL",
+		"time":     "REAL",
+		"interval": "REAL",
+		"port":     "INTEGER",
+	},
+}
+
+// sqlTextTypes are the column types of other Bro types, ex: string.
+var sqlTextTypes = map[string]string{
+	DialectPostgres:   "TEXT",
+	DialectClickHouse: "String",
+	DialectSQLite:     "TEXT",
+}
+
+// GenerateDDL returns a CREATE TABLE statement for the rows of the Bro log
+// in a SQL dialect: DialectPostgres, DialectClickHouse or DialectSQLite.
+// Columns are the fields parsed, or every field of the log when none are
+// set, named with underscores (ex: id_orig_h) and typed from the #types
+// header. Sets and vectors are arrays, except in SQLite where they are
+// text. ClickHouse columns are Nullable, since values can be unset, and
+// the table uses the MergeTree engine.
+func (p *Parser) GenerateDDL(tableName, dialect string) (string, error) {
+
+	if _, ok := sqlTypes[dialect]; !ok {
+		return "", errors.New("Unknown SQL dialect: " + dialect)
+	}
+
+	fields, types := p.fields, []string(nil)
+	var err error
+	if fields == nil {
+		fields, types, err = p.Schema()
+	} else {
+		types, err = p.rowTypes()
+	}
+	if err != nil {
+		return "", err
+	}
+	if types == nil {
+		return "", errors.New("No types header found")
+	}
+
+	var ddl strings.Builder
+	ddl.WriteString("CREATE TABLE " + quoteIdentifier(dialect, tableName) + " (\n")
+	for i, field := range fields {
+		ddl.WriteString("    " + quoteIdentifier(dialect, strings.Replace(field, ".", "_", -1)) + " " + columnType(dialect, types[i]))
+		if i < len(fields)-1 {
+			ddl.WriteString(",")
+		}
+		ddl.WriteString("\n")
+	}
+	ddl.WriteString(")")
+	if dialect == DialectClickHouse {
+		ddl.WriteString(" ENGINE = MergeTree ORDER BY tuple()")
+	}
+	ddl.WriteString(";\n")
+
+	return ddl.String(), nil
+}
+
+// columnType returns the column type of a Bro type in a dialect.
+func columnType(dialect, broType string) string {
+
+	if isContainerType(broType) {
+		element := elementColumnType(dialect, containerElementType(broType))
+		switch dialect {
+		case DialectPostgres:
+			return element + "[]"
+		case DialectClickHouse:
+			return "Array(" + element + ")"
+		}
+		return sqlTextTypes[dialect]
+	}
+
+	columnType := elementColumnType(dialect, broType)
+	if dialect == DialectClickHouse {
+		// LowCardinality must wrap Nullable, not the other way around
+		if inner := strings.TrimPrefix(columnType, "LowCardinality("); inner != columnType {
+			return "LowCardinality(Nullable(" + inner + ")"
+		}
+		return "Nullable(" + columnType + ")"
+	}
+	return columnType
+}
+
+// elementColumnType returns the column type of a Bro type that isn't a
+// container in a dialect.
+func elementColumnType(dialect, broType string) string {
+	if columnType, ok := sqlTypes[dialect][broType]; ok {
+		return columnType
+	}
+	return sqlTextTypes[dialect]
+}
+
+// quoteIdentifier quotes a table or column name in a dialect.
+func quoteIdentifier(dialect, name string) string {
+	if dialect == DialectClickHouse {
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
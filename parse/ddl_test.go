@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDDL(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\tid.orig_p\tproto\tduration\ttunnel_parents\n" +
+		"#types\ttime\taddr\tport\tenum\tinterval\tset[string]\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddl, err := parser.GenerateDDL("conn", DialectPostgres)
+	assert.Nil(err)
+	assert.Equal(ddl, "CREATE TABLE \"conn\" (\n"+
+		"    \"ts\" TIMESTAMPTZ,\n"+
+		"    \"id_orig_h\" INET,\n"+
+		"    \"id_orig_p\" INTEGER,\n"+
+		"    \"proto\" TEXT,\n"+
+		"    \"duration\" INTERVAL,\n"+
+		"    \"tunnel_parents\" TEXT[]\n"+
+		");\n", "generated the Postgres DDL incorrectly")
+
+	ddl, err = parser.GenerateDDL("conn", DialectClickHouse)
+	assert.Nil(err)
+	assert.Equal(ddl, "CREATE TABLE `conn` (\n"+
+		"    `ts` Nullable(DateTime64(6)),\n"+
+		"    `id_orig_h` Nullable(String),\n"+
+		"    `id_orig_p` Nullable(UInt16),\n"+
+		"    `proto` LowCardinality(Nullable(String)),\n"+
+		"    `duration` Nullable(Float64),\n"+
+		"    `tunnel_parents` Array(String)\n"+
+		") ENGINE = MergeTree ORDER BY tuple();\n", "generated the ClickHouse DDL incorrectly")
+
+	// Only the fields parsed are columns
+	parser.SetFields([]string{"proto", "tunnel_parents"})
+	ddl, err = parser.GenerateDDL("conn", DialectSQLite)
+	assert.Nil(err)
+	assert.Equal(ddl, "CREATE TABLE \"conn\" (\n"+
+		"    \"proto\" TEXT,\n"+
+		"    \"tunnel_parents\" TEXT\n"+
+		");\n", "generated the SQLite DDL incorrectly")
+
+	_, err = parser.GenerateDDL("conn", "oracle")
+	assert.NotNil(err, "expected an error for an unknown dialect")
+}
@@ -0,0 +1,34 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrDeadlineExceeded is the error of a pass over the Bro log that took
+// longer than the duration set with SetDeadline.
+var ErrDeadlineExceeded = errors.New("Parse deadline exceeded")
+
+// SetDeadline limits how long a pass over the entries of the Bro log may
+// take, ex: for untrusted logs parsed in a request handler. Once d has
+// elapsed, BufferRow stops, reports ErrDeadlineExceeded and closes its
+// channels, and functions returning an error (ex: ReadAll) return it. The
+// time is checked between entries and between reads, including while
+// following a log. The default, 0, means no deadline.
+func (p *Parser) SetDeadline(d time.Duration) {
+	p.deadline = d
+}
+
+// deadlineReader fails reads once its deadline has passed.
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (d *deadlineReader) Read(b []byte) (int, error) {
+	if time.Now().After(d.deadline) {
+		return 0, ErrDeadlineExceeded
+	}
+	return d.r.Read(b)
+}
@@ -0,0 +1,44 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	parser.SetDeadline(time.Minute)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 4, "a pass within the deadline should not be stopped")
+
+	// A followed log never ends on its own
+	parser.SetFollow(5 * time.Millisecond)
+	parser.SetDeadline(50 * time.Millisecond)
+	parser.CreateBuffer(10)
+	parser.CreateErrorBuffer(10)
+
+	start := time.Now()
+	go parser.BufferRow()
+
+	rows = nil
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+	assert.True(time.Since(start) < time.Second, "expected the pass to stop at the deadline")
+	assert.Equal(len(rows), 4, "rows before the deadline should be pushed")
+	assert.Equal(<-parser.Errors, ErrDeadlineExceeded, "expected the deadline to be reported")
+}
@@ -0,0 +1,136 @@
+package parse
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DirError reports the logs that failed to parse, ex: in a directory
+// parsed by ParseDirParallel, with the error of each by its path.
+type DirError struct {
+	Files map[string]error
+}
+
+func (e *DirError) Error() string {
+	paths := make([]string, 0, len(e.Files))
+	for path := range e.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := make([]string, len(paths))
+	for i, path := range paths {
+		errs[i] = path + ": " + e.Files[path].Error()
+	}
+	return strconv.Itoa(len(paths)) + " logs failed to parse: " + strings.Join(errs, "; ")
+}
+
+// ParseErrors reports the problems found while parsing a Bro log whose
+// rows were still emitted, ex: truncated or malformed entries, see
+// ParseDirParallel.
+type ParseErrors struct {
+	Errs []error
+}
+
+func (e *ParseErrors) Error() string {
+	return strconv.Itoa(len(e.Errs)) + " problems found while parsing, the first: " + e.Errs[0].Error()
+}
+
+// ParseDirParallel parses the Bro logs of dir whose names match pattern,
+// ex: "conn.*.log.gz", a log per worker at a time, and calls fn with every
+// row. Compressed logs can't be split into byte ranges, so parallelism is
+// across logs instead. The default, 0 workers, uses a worker per CPU. Logs
+// are parsed with all their fields, and fn is called from every worker at
+// once, so it must be safe for concurrent use. Logs that fail don't stop
+// the others, and their errors are returned together as a *DirError. The
+// problems found in the rows of a log, ex: a truncated last entry, are
+// its error too, as a *ParseErrors.
+func ParseDirParallel(dir, pattern string, workers int, fn func(row []string)) error {
+
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("No logs match " + filepath.Join(dir, pattern))
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]error)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if err := parseLog(path, fn); err != nil {
+					mu.Lock()
+					failed[path] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return &DirError{Files: failed}
+	}
+	return nil
+}
+
+// parseLog calls fn with every row of the Bro log at path. The problems
+// reported while parsing are collected, rather than printed.
+func parseLog(path string, fn func(row []string)) error {
+
+	p, err := NewParser(path, true)
+	if err != nil {
+		return err
+	}
+	fields, err := p.ParseAllFields()
+	if err != nil {
+		return err
+	}
+	p.SetFields(fields)
+
+	p.CreateErrorBuffer(16)
+	var reported []error
+	done := make(chan struct{})
+	go func() {
+		for err := range p.Errors {
+			reported = append(reported, err)
+		}
+		close(done)
+	}()
+
+	err = p.eachSelectedRow(func(lineNum int, row []string) error {
+		fn(p.output(row, row))
+		return nil
+	})
+	close(p.Errors)
+	<-done
+
+	if err != nil {
+		return err
+	}
+	if len(reported) > 0 {
+		return &ParseErrors{Errs: reported}
+	}
+	return nil
+}
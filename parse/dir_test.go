@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	var expected []string
+	for i := 0; i < 4; i++ {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write([]byte(followHeader))
+		for j := 0; j < 10; j++ {
+			uid := "C" + strconv.Itoa(i*10+j)
+			gz.Write([]byte("1452684903.908400\t" + uid + "\ttcp\n"))
+			expected = append(expected, uid)
+		}
+		gz.Close()
+
+		path := filepath.Join(dir, "conn.0"+strconv.Itoa(i)+".log.gz")
+		if err := os.WriteFile(path, compressed.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Strings(expected)
+
+	var mu sync.Mutex
+	var uids []string
+	err := ParseDirParallel(dir, "conn.*.log.gz", 2, func(row []string) {
+		mu.Lock()
+		uids = append(uids, row[1])
+		mu.Unlock()
+	})
+	assert.Nil(err)
+	sort.Strings(uids)
+	assert.Equal(uids, expected, "expected the rows of every log")
+
+	// A corrupt log is reported, without stopping the others
+	corrupt := filepath.Join(dir, "conn.04.log.gz")
+	if err := os.WriteFile(corrupt, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var rows int
+	err = ParseDirParallel(dir, "conn.*.log.gz", 0, func(row []string) {
+		mu.Lock()
+		rows++
+		mu.Unlock()
+	})
+	if assert.IsType(err, &DirError{}) {
+		assert.Equal(len(err.(*DirError).Files), 1, "expected only the corrupt log to fail")
+		assert.Contains(err.(*DirError).Files, corrupt)
+	}
+	assert.Equal(rows, 40, "expected the rows of the other logs")
+
+	// Problems in the rows of a log are its error, not printed
+	var truncated bytes.Buffer
+	gz := gzip.NewWriter(&truncated)
+	gz.Write([]byte(followHeader + "1452684903.908400\tC50\ttcp\n1452684904.908400\tC51"))
+	gz.Close()
+	if err := os.WriteFile(corrupt, truncated.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rows = 0
+	err = ParseDirParallel(dir, "conn.*.log.gz", 0, func(row []string) {
+		mu.Lock()
+		rows++
+		mu.Unlock()
+	})
+	if assert.IsType(err, &DirError{}) && assert.IsType(err.(*DirError).Files[corrupt], &ParseErrors{}) {
+		errs := err.(*DirError).Files[corrupt].(*ParseErrors).Errs
+		assert.Equal(len(errs), 1, "expected the truncated entry to be reported")
+		assert.IsType(errs[0], &TruncatedError{})
+	}
+	assert.Equal(rows, 41, "expected the complete rows of the truncated log")
+
+	err = ParseDirParallel(dir, "dns.*.log.gz", 0, func(row []string) {})
+	assert.EqualError(err, "No logs match "+filepath.Join(dir, "dns.*.log.gz"))
+}
@@ -0,0 +1,45 @@
+package parse
+
+import (
+	"errors"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// The encodings SetEncoding reads Bro logs in.
+const (
+	EncodingUTF8   = "utf-8"
+	EncodingLatin1 = "latin-1"
+)
+
+var encodings = map[string]encoding.Encoding{
+	EncodingUTF8:   unicode.UTF8,
+	EncodingLatin1: charmap.ISO8859_1,
+}
+
+// SetEncoding sets the encoding the Bro log is read in, so that rows are
+// always valid UTF-8, ex: for logs with Latin-1 user agents. The log is
+// transcoded from EncodingLatin1, or read as EncodingUTF8 with invalid
+// sequences replaced by U+FFFD. Without an encoding, bytes are read as they
+// are. The tee gets the bytes of the log before they are transcoded.
+func (p *Parser) SetEncoding(enc string) error {
+	textEncoding, ok := encodings[enc]
+	if !ok {
+		return errors.New("Unknown encoding: " + enc)
+	}
+	p.textEncoding = textEncoding
+	return nil
+}
+
+// decodeReader returns a reader transcoding r to UTF-8 from the encoding
+// of the Bro log.
+func (p *Parser) decodeReader(r io.Reader) io.Reader {
+	if p.textEncoding == nil {
+		return r
+	}
+	return transform.NewReader(r, p.textEncoding.NewDecoder())
+}
@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	// Latin-1 user agents, with an é (0xe9) in the first 512 bytes
+	fsys := fstest.MapFS{"http.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tuser_agent\n" +
+		"#types\ttime\tstring\n" +
+		"1300475167.096535\tcaf\xe9\n" +
+		"1300475168.096535\tna\xefve\n")}}
+
+	parser, err := NewParserFS(fsys, "http.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.False(utf8.ValidString(rows[0][1]), "expected bytes as they are without an encoding")
+
+	assert.Nil(parser.SetEncoding(EncodingLatin1))
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"1300475167.096535", "café"},
+		{"1300475168.096535", "naïve"},
+	}, "expected Latin-1 transcoded to UTF-8")
+
+	assert.Nil(parser.SetEncoding(EncodingUTF8))
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0][1], "caf\uFFFD", "expected invalid UTF-8 to be replaced")
+
+	assert.EqualError(parser.SetEncoding("ebcdic"), "Unknown encoding: ebcdic", "expected an error for an unknown encoding")
+}
@@ -0,0 +1,86 @@
+package parse
+
+import (
+	"bytes"
+	"io"
+)
+
+// estimateChunk is how many bytes EstimateRows samples from the start of
+// the Bro log.
+const estimateChunk = 64 * 1024
+
+// EstimateRows estimates the number of entries in the Bro log without
+// reading it whole, ex: to size a buffer for a very large log. The average
+// length of the entries in its first 64KB is sampled, and the size of the
+// log is divided by it. Logs that fit in the sample are counted exactly,
+// and compressed logs, framed logs or logs that can't be seeked are
+// counted in full.
+func (p *Parser) EstimateRows() (int, error) {
+
+	file, size, seekable := p.openSeekable()
+	if !seekable {
+		return p.countRows()
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return -1, err
+	}
+
+	chunk := make([]byte, estimateChunk)
+	n, err := io.ReadFull(file, chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return -1, err
+	}
+	chunk = chunk[:n]
+	whole := int64(n) == size
+
+	var entries, entryBytes, headerBytes int64
+	for len(chunk) > 0 {
+		end := bytes.IndexByte(chunk, '\n')
+		if end < 0 {
+			// The last line of the sample is cut off, unless it is the last of the log
+			if !whole {
+				break
+			}
+			end = len(chunk) - 1
+		}
+
+		line := chunk[:end+1]
+		chunk = chunk[end+1:]
+
+		switch {
+		case len(bytes.TrimSpace(line)) == 0:
+		case line[0] == '#':
+			headerBytes += int64(len(line))
+		default:
+			entries++
+			entryBytes += int64(len(line))
+		}
+	}
+
+	if whole {
+		return int(entries), nil
+	}
+	if entries == 0 {
+		return p.countRows()
+	}
+
+	return int((size - headerBytes) * entries / entryBytes), nil
+}
+
+// countRows counts every row of the Bro log, selected or not, for the logs
+// EstimateRows can't sample.
+func (p *Parser) countRows() (int, error) {
+
+	count := 0
+	err := p.eachRow(func(lineNum int, row []string) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
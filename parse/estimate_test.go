@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateRows(t *testing.T) {
+	assert := assert.New(t)
+
+	var log strings.Builder
+	log.WriteString(followHeader)
+	for i := 0; i < 10000; i++ {
+		log.WriteString("1452684903.908400\tC" + strconv.Itoa(100000+i) + "\ttcp\n")
+	}
+	log.WriteString("#close\t2016-01-13-07-00-00\n")
+
+	fsys := fstest.MapFS{
+		"big.log":   {Data: []byte(log.String())},
+		"small.log": {Data: []byte(followHeader + "1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n\n1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp")},
+	}
+
+	parser, err := NewParserFS(fsys, "big.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	estimate, err := parser.EstimateRows()
+	assert.Nil(err)
+	assert.InDelta(estimate, 10000, 100, "estimated the rows of a large log incorrectly")
+
+	parser, err = NewParserFS(fsys, "small.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	estimate, err = parser.EstimateRows()
+	assert.Nil(err)
+	assert.Equal(estimate, 2, "expected the rows of a small log to be counted")
+}
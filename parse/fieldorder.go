@@ -0,0 +1,49 @@
+package parse
+
+// SetFieldOrder sets a preferred order of the fields when parsing all
+// fields, ex: the fields of the log in the config, so that every field is
+// parsed but rows are in that order rather than the order of the log.
+// Fields of the log that aren't in order follow, in the order of the log,
+// and fields of order that aren't in the log are ignored. The fields of the
+// parser (see Fields) are reordered the same way. Without all fields,
+// rows are already in the order of the fields set.
+func (p *Parser) SetFieldOrder(order []string) {
+	p.fieldOrder = order
+	p.applyFieldOrder()
+}
+
+// applyFieldOrder reorders the fields parsed by the preferred order.
+func (p *Parser) applyFieldOrder() {
+	if !p.allFields || p.fieldOrder == nil || p.fields == nil {
+		return
+	}
+	if ordered := orderFields(p.fields, p.fieldOrder); !equalFields(ordered, p.fields) {
+		p.SetFields(ordered)
+	}
+}
+
+// orderFields returns fields with the ones in order first, in that order,
+// and the others after, in their own order.
+func orderFields(fields, order []string) []string {
+
+	inFields := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		inFields[field] = true
+	}
+
+	ordered := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range order {
+		if inFields[field] && !seen[field] {
+			ordered = append(ordered, field)
+			seen[field] = true
+		}
+	}
+	for _, field := range fields {
+		if !seen[field] {
+			ordered = append(ordered, field)
+		}
+	}
+
+	return ordered
+}
@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFieldOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFieldOrder([]string{"proto", "uid", "service"})
+	assert.Equal(parser.Fields(), []string{"proto", "uid", "ts", "id.orig_h"}, "reordered the fields incorrectly")
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 4, "expected every row")
+	assert.Equal(rows[0], []string{"tcp", "CbOiIv2wbbH7F25W21", "1452684903.908400", "10.1.20.227"}, "reordered the row incorrectly")
+
+	value, err := parser.Value(rows[1], "id.orig_h")
+	assert.Nil(err)
+	assert.Equal(value, "10.1.20.228", "values should be found by the reordered fields")
+
+	// Fields set after the order are reordered too
+	parser.SetFields(fields)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[3], []string{"icmp", "ClEkJM2Vm5giqnMf4h", "1452684906.000000", "10.1.20.230"}, "reordered the row incorrectly")
+}
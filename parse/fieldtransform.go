@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"strconv"
+)
+
+// fieldTransform is a transform of the values of the field at index.
+type fieldTransform struct {
+	field string
+	index int
+	fn    func(string) (string, error)
+}
+
+// FieldTransformError reports a value a field transform failed on. The
+// value is emitted as it was read.
+type FieldTransformError struct {
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *FieldTransformError) Error() string {
+	return "Couldn't transform " + e.Field + " on line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+// SetFieldTransforms sets functions transforming the values of single
+// fields, by field name, ex: to lowercase proto or to redact uri. They are
+// applied to every row before the filter and the Parse() functions. When a
+// transform fails, the value is kept as it was read and a
+// FieldTransformError is reported. The fields must be ones parsed.
+func (p *Parser) SetFieldTransforms(transforms map[string]func(string) (string, error)) {
+	p.fieldTransforms = transforms
+	p.transformIndex = nil
+}
+
+// transformFields returns a row with its field transforms applied.
+func (p *Parser) transformFields(lineNum int, row []string) ([]string, error) {
+
+	if p.fieldTransforms == nil {
+		return row, nil
+	}
+
+	// Indexes are resolved once for the fields parsed
+	if p.transformIndex == nil {
+		p.transformIndex = make([]fieldTransform, 0, len(p.fieldTransforms))
+		for field, fn := range p.fieldTransforms {
+			index, err := getIndex(p.fields, field)
+			if err != nil {
+				p.transformIndex = nil
+				return nil, err
+			}
+			p.transformIndex = append(p.transformIndex, fieldTransform{field: field, index: index, fn: fn})
+		}
+	}
+
+	transformed := append([]string{}, row...)
+	for _, transform := range p.transformIndex {
+		if transform.index >= len(row) {
+			continue
+		}
+
+		value, err := transform.fn(row[transform.index])
+		if err != nil {
+			p.reportError(&FieldTransformError{Line: lineNum, Field: transform.field, Err: err})
+			continue
+		}
+		transformed[transform.index] = value
+	}
+
+	return transformed, nil
+}
@@ -0,0 +1,44 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFieldTransforms(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+	parser.CreateErrorBuffer(10)
+	parser.SetFieldTransforms(map[string]func(string) (string, error){
+		"proto": func(proto string) (string, error) { return strings.ToUpper(proto), nil },
+		"uid": func(uid string) (string, error) {
+			if uid == "CHhAvVGS1DHFjwGM9" {
+				return "", errors.New("unknown uid")
+			}
+			return uid[:4], nil
+		},
+	})
+	parser.SetFilter(func(row []string) bool { return row[1] != "ICMP" })
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"CbOi", "TCP"}, {"C7fI", "UDP"}, {"CHhAvVGS1DHFjwGM9", "TCP"}}, "transformed the fields incorrectly")
+
+	if assert.Equal(len(parser.Errors), 1, "expected the failed transform to be reported") {
+		transformErr := (<-parser.Errors).(*FieldTransformError)
+		assert.Equal(transformErr.Field, "uid", "reported the wrong field")
+		assert.Equal(transformErr.Line, 11, "reported the wrong line")
+	}
+
+	parser.SetFieldTransforms(map[string]func(string) (string, error){"uri": nil})
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected an error for a field that isn't parsed")
+}
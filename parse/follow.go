@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SetFollow makes BufferRow keep reading the Bro log as it is written to,
+// like tail -f, checking for new data every pollInterval once it reaches the
+// end of the log. A partial last line is held until the rest of it is
+// written. Following continues until Stop is called.
+func (p *Parser) SetFollow(pollInterval time.Duration) {
+	p.follow = pollInterval
+	p.stop = make(chan struct{})
+	p.stopOnce = new(sync.Once)
+}
+
+// Stop ends following the Bro log. Rows already read are still pushed,
+// and BufferRow returns once it reaches the end of the data written so far.
+func (p *Parser) Stop() {
+	if p.stop != nil {
+		p.stopOnce.Do(func() { close(p.stop) })
+	}
+}
+
+// followReader waits for more data instead of returning io.EOF, until
+// the parser is stopped or timeout fires.
+type followReader struct {
+	r            io.Reader
+	pollInterval time.Duration
+	stop         chan struct{}
+	timeout      <-chan time.Time
+}
+
+func (f *followReader) Read(b []byte) (int, error) {
+	for {
+		n, err := f.r.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+
+		select {
+		case <-f.stop:
+			return 0, io.EOF
+		case <-f.timeout:
+			return 0, ErrDeadlineExceeded
+		case <-time.After(f.pollInterval):
+		}
+	}
+}
+
+// TruncatedError reports a last line that is missing both its trailing
+// newline and some of its values, usually because the log was still being
+// written to when it was read.
+type TruncatedError struct {
+	Line  int
+	Entry string
+}
+
+func (e *TruncatedError) Error() string {
+	return "Entry on line " + strconv.Itoa(e.Line) + " is truncated: " + e.Entry
+}
+
+// lineSplitter is bufio.ScanLines, splitFrame for framed logs or the
+// record splitter of the parser, recording whether the last line returned
+// was missing its trailing newline, and the byte offset it started at.
+type lineSplitter struct {
+	framed       bool
+	records      bufio.SplitFunc
+	unterminated bool
+	start        int64
+	offset       int64
+}
+
+func (l *lineSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if l.framed && l.records == nil {
+		return l.splitFrame(data, atEOF)
+	}
+
+	split := l.records
+	if split == nil {
+		split = bufio.ScanLines
+	}
+
+	advance, token, err := split(data, atEOF)
+	l.unterminated = atEOF && advance > 0 && advance == len(data) && data[len(data)-1] != '\n'
+	if token != nil {
+		l.start = l.offset
+	}
+	l.offset += int64(advance)
+	return advance, token, err
+}
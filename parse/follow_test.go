@@ -0,0 +1,144 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const followHeader = "#separator \\x09\n#fields\tts\tuid\tproto\n#types\ttime\tstring\tenum\n"
+
+func TestTruncatedLastEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(followHeader +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"1452684904.908400\tC7fIlM")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.CreateBuffer(10)
+	parser.CreateErrorBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows int
+	for range parser.Row {
+		rows++
+	}
+	assert.Equal(rows, 1, "truncated entry should not be pushed")
+
+	var errs []error
+	for err := range parser.Errors {
+		errs = append(errs, err)
+	}
+	if assert.Equal(len(errs), 1, "expected the truncated entry to be reported") {
+		truncated, ok := errs[0].(*TruncatedError)
+		assert.True(ok, "expected a *TruncatedError")
+		assert.Equal(truncated.Line, 5, "reported the wrong line")
+	}
+}
+
+func TestFollowHoldsPartialEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "conn.log")
+	err := os.WriteFile(path, []byte(followHeader+"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n1452684904.908400\tC7fIlM"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.SetFollow(5 * time.Millisecond)
+	parser.CreateBuffer(10)
+	parser.CreateErrorBuffer(10)
+
+	go parser.BufferRow()
+
+	row := <-parser.Row
+	assert.Equal(row[1], "CbOiIv2wbbH7F25W21", "parsed entries incorrectly")
+
+	// The rest of the partial entry is written while following
+	time.Sleep(20 * time.Millisecond)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("ZDuRiqjpYbb\tudp\n")
+	file.Close()
+
+	row = <-parser.Row
+	assert.Equal(row, []string{"1452684904.908400", "C7fIlMZDuRiqjpYbb", "udp"}, "partial entry should be held until complete")
+
+	parser.Stop()
+
+	_, open := <-parser.Row
+	assert.False(open, "Row should be closed once stopped")
+	assert.Equal(len(parser.Errors), 0, "no entries should be reported as truncated")
+}
+
+func TestFollowStopsAtLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "conn.log")
+	err := os.WriteFile(path, []byte(followHeader+"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.SetFollow(5 * time.Millisecond)
+	parser.SetLimit(2)
+	parser.CreateBuffer(10)
+	defer parser.Stop()
+
+	go parser.BufferRow()
+
+	var rows int
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, open := <-parser.Row:
+			if !open {
+				assert.Equal(rows, 2, "expected the rows up to the limit")
+				return
+			}
+			rows++
+		case <-timeout:
+			t.Fatal("Row should be closed once the limit is reached, without another row")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// tsvEscaper escapes the characters of values that would split the columns
+// or rows of TSV.
+var tsvEscaper = strings.NewReplacer("\t", `\x09`, "\n", `\x0a`, "\r", `\x0d`)
+
+// Format is a format WriteTo writes rows in.
+type Format string
+
+// The formats WriteTo writes rows in.
+const (
+	FormatTSV  Format = "tsv"
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+// SetOmitUnset sets whether unset fields are left out of the JSON objects
+// written by WriteTo and of the TypedMaps pushed by BufferTypedRowMap,
+// instead of being written as their unset value, the way Zeek writes its
+// JSON logs. This keeps documents small for search backends.
+func (p *Parser) SetOmitUnset(enabled bool) {
+	p.omitUnset = enabled
+}
+
+// SetFormat sets the format of the rows written by WriteTo: FormatTSV for
+// tab separated values, with the tabs and newlines of values escaped like
+// a Bro log escapes its separator (ex: \x09), FormatCSV for CSV with a
+// header line of the fields, or FormatJSON for a JSON object per line. The
+// default is FormatTSV.
+func (p *Parser) SetFormat(format Format) error {
+	switch format {
+	case FormatTSV, FormatCSV, FormatJSON:
+		p.format = format
+		return nil
+	}
+	return errors.New("Unknown format: " + string(format))
+}
+
+// WriteTo writes every row of the Bro log to w, in the format set with
+// SetFormat, and returns the number of bytes written. Rows are the same as
+// the ones BufferRow pushes, so that the parser is an io.WriterTo feeding
+// any io.Writer, ex: a file, a socket or a gzip.Writer.
+func (p *Parser) WriteTo(w io.Writer) (int64, error) {
+
+	counter := &countingWriter{w: w}
+	out := bufio.NewWriter(counter)
+
+	var csvOut *csv.Writer
+	var buf bytes.Buffer
+	var fields []string
+	wroteHeader := false
+
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		row = p.output(row, row)
+		if fields == nil {
+			fields = p.outputFields()
+		}
+
+		switch p.format {
+		case FormatCSV:
+			if csvOut == nil {
+				csvOut = csv.NewWriter(out)
+			}
+			if !wroteHeader {
+				if err := csvOut.Write(fields); err != nil {
+					return err
+				}
+				wroteHeader = true
+			}
+			return csvOut.Write(row)
+		case FormatJSON:
+			buf.Reset()
+			jsonFields := fields
+			if p.omitUnset {
+				jsonFields, row = withoutUnset(jsonFields, row, p.unsetValue())
+			}
+			if err := writeJSONObject(&buf, jsonFields, row); err != nil {
+				return err
+			}
+			_, err := out.Write(buf.Bytes())
+			return err
+		}
+
+		escaped := make([]string, len(row))
+		for i, value := range row {
+			escaped[i] = tsvEscaper.Replace(value)
+		}
+		_, err := out.WriteString(strings.Join(escaped, "\t") + "\n")
+		return err
+	})
+	if csvOut != nil && err == nil {
+		csvOut.Flush()
+		err = csvOut.Error()
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+
+	return counter.n, err
+}
+
+// outputFields returns the fields of the rows emitted, including the row
+// ID, computed and source fields.
+func (p *Parser) outputFields() []string {
+
+	var fields []string
+	if p.rowID {
+		fields = append(fields, RowIDField)
+	}
+	fields = append(fields, p.fields...)
+	for _, computed := range p.computedFields {
+		fields = append(fields, computed.name)
+	}
+	if p.sourceFields {
+		fields = append(fields, SourceFileField, LogPathField)
+	}
+
+	return fields
+}
+
+// countingWriter tracks the number of bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTo(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tproto\n" +
+		"#types\taddr\tenum\n" +
+		"10.1.20.227\ttcp\n" +
+		"10.1.20.228\tudp, \"quoted\"\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	var _ io.WriterTo = parser
+
+	for format, expected := range map[Format]string{
+		FormatTSV:  "10.1.20.227\ttcp\n10.1.20.228\tudp, \"quoted\"\n",
+		FormatCSV:  "id.orig_h,proto\n10.1.20.227,tcp\n10.1.20.228,\"udp, \"\"quoted\"\"\"\n",
+		FormatJSON: `{"id.orig_h":"10.1.20.227","proto":"tcp"}` + "\n" + `{"id.orig_h":"10.1.20.228","proto":"udp, \"quoted\""}` + "\n",
+	} {
+		assert.Nil(parser.SetFormat(format))
+
+		var buf bytes.Buffer
+		n, err := parser.WriteTo(&buf)
+		assert.Nil(err)
+		assert.Equal(buf.String(), expected, "wrote "+string(format)+" incorrectly")
+		assert.Equal(n, int64(buf.Len()), "expected the number of bytes written")
+	}
+
+	// Row IDs are a field of the rows written
+	parser.SetRowID(true)
+	assert.Nil(parser.SetFormat(FormatCSV))
+	var buf bytes.Buffer
+	_, err = parser.WriteTo(&buf)
+	assert.Nil(err)
+	assert.Equal(buf.String()[:len("_row_id,id.orig_h,proto\n")], "_row_id,id.orig_h,proto\n", "expected the row ID in the header")
+
+	assert.NotNil(parser.SetFormat("xml"), "expected an error for an unknown format")
+}
+
+func TestWriteToEscapesTSV(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"http.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\tuser_agent\n" +
+		"#types\tstring\tstring\n" +
+		"C1\tcurl\\x09tab\n" +
+		"C2\tcurl-newline\n")}}
+
+	parser, err := NewParserFS(fsys, "http.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFieldTransforms(map[string]func(string) (string, error){
+		"user_agent": func(value string) (string, error) {
+			return strings.Replace(value, "-", "\r\n", 1), nil
+		},
+	})
+
+	var buf bytes.Buffer
+	_, err = parser.WriteTo(&buf)
+	assert.Nil(err)
+	assert.Equal(buf.String(), "C1\tcurl\\x09tab\nC2\tcurl\\x0d\\x0anewline\n", "expected tabs and newlines in values to be escaped")
+}
+
+func TestSetOmitUnset(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tservice\n" +
+		"#types\taddr\tstring\n" +
+		"10.1.20.227\t-\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	assert.Nil(parser.SetFormat(FormatJSON))
+
+	var buf bytes.Buffer
+	_, err = parser.WriteTo(&buf)
+	assert.Nil(err)
+	assert.Equal(buf.String(), `{"id.orig_h":"10.1.20.227","service":"-"}`+"\n", "expected unset fields by default")
+
+	parser.SetOmitUnset(true)
+	parser.SetUnsetOutput("")
+	buf.Reset()
+	_, err = parser.WriteTo(&buf)
+	assert.Nil(err)
+	assert.Equal(buf.String(), `{"id.orig_h":"10.1.20.227"}`+"\n", "expected unset fields to be omitted")
+
+	parser.CreateTypedMapBuffer(1)
+	go parser.BufferTypedRowMap()
+	typedMap := <-parser.TypedRowMap
+	for range parser.TypedRowMap {
+	}
+	_, ok := typedMap.Get("service")
+	assert.False(ok, "expected unset fields to be omitted from typed rows")
+}
@@ -0,0 +1,115 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// The framings SetFraming reads Bro logs with.
+const (
+	FramingNewline        = "newline"
+	FramingLengthPrefixed = "length-prefixed"
+)
+
+// maxFramePrefix is the longest length prefix of a frame, with its space.
+const maxFramePrefix = 20
+
+// SetFraming sets how the lines of the Bro log are delimited. Lines end
+// with a newline with FramingNewline, the default. With
+// FramingLengthPrefixed every line is framed, as by length delimited
+// transports and RFC 6587 octet counting: the number of bytes of the line
+// in decimal, a space, then the line, which may hold newlines of its own.
+// Newlines between frames are skipped. Framing applies to entries and
+// header lines alike, and to the logs written by Extract, WriteLog and
+// RotatingWriter. Framed logs are always read from their start, so
+// TimeSpan and EstimateRows read them whole, and CountLines counts frames.
+func (p *Parser) SetFraming(framing string) error {
+	switch framing {
+	case FramingNewline, FramingLengthPrefixed:
+		p.framing = framing
+		return nil
+	}
+	return errors.New("Unknown framing: " + framing)
+}
+
+// SetRecordSplitter sets how the Bro log is split into records, for logs
+// whose records span more than one line, ex: pretty printed JSON. split
+// works as a bufio.SplitFunc, returning every record as a token, header
+// lines included, and takes precedence over the framing. A nil split goes
+// back to the framing. As with framed logs, the log is always read from
+// its start, and CountLines counts records.
+func (p *Parser) SetRecordSplitter(split func(data []byte, atEOF bool) (advance int, token []byte, err error)) {
+	p.recordSplitter = split
+}
+
+// customFraming reports whether the lines of the Bro log aren't simply
+// newline delimited, so that it must be read with newScanner.
+func (p *Parser) customFraming() bool {
+	return p.framing == FramingLengthPrefixed || p.recordSplitter != nil
+}
+
+// writeLine writes a line of a Bro log to out, framed the way the log is
+// read.
+func (p *Parser) writeLine(out *bufio.Writer, line string) error {
+	if p.framing == FramingLengthPrefixed {
+		out.WriteString(strconv.Itoa(len(line)) + " ")
+	}
+	out.WriteString(line)
+	return out.WriteByte('\n')
+}
+
+// newScanner returns a scanner of the lines of the Bro log read from r,
+// along with the splitter it uses.
+func (p *Parser) newScanner(r io.Reader) (*bufio.Scanner, *lineSplitter) {
+	splitter := &lineSplitter{framed: p.framing == FramingLengthPrefixed, records: p.recordSplitter}
+	scanner := bufio.NewScanner(r)
+	scanner.Split(splitter.split)
+	return scanner, splitter
+}
+
+// splitFrame is split for length prefixed lines.
+func (l *lineSplitter) splitFrame(data []byte, atEOF bool) (int, []byte, error) {
+
+	skip := 0
+	for skip < len(data) && (data[skip] == '\n' || data[skip] == '\r') {
+		skip++
+	}
+	frame := data[skip:]
+	if len(frame) == 0 {
+		l.offset += int64(skip)
+		return skip, nil, nil
+	}
+
+	space := bytes.IndexByte(frame, ' ')
+	if space < 0 {
+		if !atEOF && len(frame) < maxFramePrefix {
+			l.offset += int64(skip)
+			return skip, nil, nil
+		}
+		return 0, nil, errors.New("Invalid frame, it has no length prefix")
+	}
+
+	length, err := strconv.Atoi(string(frame[:space]))
+	if err != nil || length < 0 || space >= maxFramePrefix {
+		return 0, nil, errors.New("Invalid frame length: " + string(frame[:space]))
+	}
+
+	end := space + 1 + length
+	l.unterminated = len(frame) < end
+	if l.unterminated {
+		if !atEOF {
+			l.offset += int64(skip)
+			return skip, nil, nil
+		}
+		// A last frame cut off is returned as is, like a last line
+		// without a newline
+		end = len(frame)
+	}
+
+	l.start = l.offset + int64(skip)
+	l.offset += int64(skip + end)
+	return skip + end, frame[space+1 : end], nil
+}
@@ -0,0 +1,168 @@
+package parse
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// frameLines frames every line with its length.
+func frameLines(lines ...string) string {
+	var framed strings.Builder
+	for _, line := range lines {
+		framed.WriteString(strconv.Itoa(len(line)) + " " + line + "\n")
+	}
+	return framed.String()
+}
+
+func TestSetFraming(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"http.log": {Data: []byte(frameLines(
+			"#separator \\x09",
+			"#fields\tts\tuser_agent",
+			"#types\ttime\tstring",
+			"1300475167.096535\tMozilla/5.0\nInjected",
+			"1300475168.096535\tcurl/7.0",
+		))},
+		"broken.log": {Data: []byte("#separator \\x09\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "http.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(parser.SetFraming(FramingLengthPrefixed))
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "user_agent"}, "expected the header read from frames")
+	parser.SetFields(fields)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"1300475167.096535", "Mozilla/5.0\nInjected"},
+		{"1300475168.096535", "curl/7.0"},
+	}, "expected newlines kept inside frames")
+
+	parser, err = NewParserFS(fsys, "broken.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(parser.SetFraming(FramingLengthPrefixed))
+	_, _, err = parser.Schema()
+	assert.NotNil(err, "expected an error for a line without a length prefix")
+
+	assert.NotNil(parser.SetFraming("chunked"), "expected an error for an unknown framing")
+}
+
+func TestSetRecordSplitter(t *testing.T) {
+	assert := assert.New(t)
+
+	// Pretty printed JSON, with a record per top level object
+	log := "{\n" +
+		"  \"ts\": 1452684903.9084,\n" +
+		"  \"uid\": \"CbOiIv2wbbH7F25W21\",\n" +
+		"  \"proto\": \"tcp\"\n" +
+		"}\n" +
+		"{\n" +
+		"  \"ts\": 1452684904.9084,\n" +
+		"  \"uid\": \"C7fIlMZDuRiqjpYbb\",\n" +
+		"  \"proto\": \"udp\"\n" +
+		"}\n"
+	fsys := fstest.MapFS{"conn.json": {Data: []byte(log)}}
+
+	parser, err := NewParserFS(fsys, "conn.json", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetRecordSplitter(func(data []byte, atEOF bool) (int, []byte, error) {
+		if end := bytes.Index(data, []byte("\n}\n")); end >= 0 {
+			return end + 3, data[:end+2], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "proto"}, "parsed fields incorrectly")
+
+	parser.SetFields(fields)
+	parser.CreateBuffer(10)
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+	assert.Equal(rows, [][]string{
+		{"1452684903.9084", "CbOiIv2wbbH7F25W21", "tcp"},
+		{"1452684904.9084", "C7fIlMZDuRiqjpYbb", "udp"},
+	}, "expected a row per record")
+
+	count, err := parser.CountLines()
+	assert.Nil(err)
+	assert.Equal(count, 2, "expected records to be counted")
+}
+
+func TestFramingWholeLog(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"http.log": {Data: []byte(frameLines(
+		"#separator \\x09",
+		"#fields\tts\tuser_agent",
+		"#types\ttime\tstring",
+		"1300475167.096535\tMozilla/5.0\nInjected",
+		"1300475168.096535\tcurl/7.0\n1300475999.000000",
+		"#close\t2011-03-18-19-06-08",
+	))}}
+
+	parser, err := NewParserFS(fsys, "http.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(parser.SetFraming(FramingLengthPrefixed))
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	count, err := parser.CountLines()
+	assert.Nil(err)
+	assert.Equal(count, 6, "expected frames to be counted, not newlines")
+
+	rows, err := parser.EstimateRows()
+	assert.Nil(err)
+	assert.Equal(rows, 2, "expected the entries of the frames")
+
+	first, last, err := parser.TimeSpan()
+	assert.Nil(err)
+	assert.Equal(first, time.Unix(1300475167, 96535000).UTC(), "expected the ts of the first frame")
+	assert.Equal(last, time.Unix(1300475168, 96535000).UTC(), "expected the ts of the last frame, not of a newline inside it")
+
+	// The extracted log is framed the same way
+	var out bytes.Buffer
+	err = parser.Extract(&out, func(entry []string) bool { return strings.HasPrefix(entry[1], "curl") })
+	assert.Nil(err)
+	assert.Equal(out.String(), frameLines(
+		"#separator \\x09",
+		"#fields\tts\tuser_agent",
+		"#types\ttime\tstring",
+		"1300475168.096535\tcurl/7.0\n1300475999.000000",
+		"#close\t2011-03-18-19-06-08",
+	), "expected the entries and header lines read and written as frames")
+}
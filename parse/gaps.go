@@ -0,0 +1,133 @@
+package parse
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Gap is a lapse in the time covered by rotated Bro logs, ex: while Bro was
+// down, from the #close of the log After to the #open of the log Before.
+type Gap struct {
+	After  string
+	Before string
+	Start  time.Time
+	End    time.Time
+}
+
+// Duration returns how long coverage lapsed for.
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// coverage is the time a Bro log was open for.
+type coverage struct {
+	path  string
+	open  time.Time
+	close time.Time
+}
+
+// DetectCoverageGaps reads the #open and #close headers of rotated Bro logs
+// and returns the gaps longer than maxGap between one log closing and the
+// next one opening, in order. Logs are ordered by #open, so paths can be
+// given in any order. Only the latest log may have no #close, as it may
+// still be written to. Header times are read as UTC.
+func DetectCoverageGaps(paths []string, maxGap time.Duration) ([]Gap, error) {
+
+	logs := make([]coverage, 0, len(paths))
+	for _, path := range paths {
+		log, err := readCoverage(path)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].open.Before(logs[j].open)
+	})
+
+	var gaps []Gap
+	for i := 1; i < len(logs); i++ {
+		previous, next := logs[i-1], logs[i]
+		if previous.close.IsZero() {
+			return nil, errors.New("No #close header found in " + previous.path)
+		}
+		if next.open.Sub(previous.close) > maxGap {
+			gaps = append(gaps, Gap{After: previous.path, Before: next.path, Start: previous.close, End: next.open})
+		}
+	}
+
+	return gaps, nil
+}
+
+// readCoverage reads the #open header at the top of a Bro log, and the
+// #close header at its end.
+func readCoverage(path string) (coverage, error) {
+
+	log := coverage{path: path}
+
+	p, err := NewParser(path, true)
+	if err != nil {
+		return log, err
+	}
+
+	h, err := p.readHeader()
+	if err != nil {
+		return log, err
+	}
+	if h.open == "" {
+		return log, errors.New("No #open header found in " + path)
+	}
+	if log.open, err = time.Parse(headerTimeFormat, h.open); err != nil {
+		return log, err
+	}
+
+	closeValue, err := p.closeHeader(h)
+	if err != nil || closeValue == "" {
+		return log, err
+	}
+	log.close, err = time.Parse(headerTimeFormat, closeValue)
+	return log, err
+}
+
+// closeHeader returns the value of the #close header of the Bro log, read
+// backward from its end when possible, or "" if it has none.
+func (p *Parser) closeHeader(h *header) (string, error) {
+
+	closeValue := func(line string) string {
+		_, value := h.directive(line)
+		return value
+	}
+
+	if file, size, ok := p.openSeekable(); ok {
+		defer file.Close()
+
+		var value string
+		scanBackward(file, size, func(line string) bool {
+			if strings.HasPrefix(line, "#close") {
+				value = closeValue(line)
+				return true
+			}
+			// #close is only written after the last entry
+			return line != "" && line[0] != '#'
+		})
+		return value, nil
+	}
+
+	file, err := p.open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var value string
+	scanner, _ := p.newScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "#close") {
+			value = closeValue(line)
+		}
+	}
+	return value, scanner.Err()
+}
@@ -0,0 +1,45 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCoverageGaps(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	rotated := func(name, open, close string) string {
+		log := "#separator \\x09\n#path\tconn\n#open\t" + open + "\n" + followHeader[len("#separator \\x09\n"):] +
+			"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n"
+		if close != "" {
+			log += "#close\t" + close + "\n"
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	first := rotated("conn.10:00:00-11:00:00.log", "2016-01-13-10-00-00", "2016-01-13-11-00-00")
+	second := rotated("conn.11:00:00-12:00:00.log", "2016-01-13-11-00-01", "2016-01-13-12-00-00")
+	third := rotated("conn.12:30:00-13:00:00.log", "2016-01-13-12-30-00", "")
+
+	gaps, err := DetectCoverageGaps([]string{third, first, second}, time.Minute)
+	assert.Nil(err)
+	assert.Equal(gaps, []Gap{{
+		After:  second,
+		Before: third,
+		Start:  time.Date(2016, 1, 13, 12, 0, 0, 0, time.UTC),
+		End:    time.Date(2016, 1, 13, 12, 30, 0, 0, time.UTC),
+	}}, "expected the half hour Bro was down")
+	assert.Equal(gaps[0].Duration(), 30*time.Minute, "gap duration is incorrect")
+
+	_, err = DetectCoverageGaps([]string{third, rotated("conn.13:00:00.log", "2016-01-13-13-00-00", "")}, time.Minute)
+	assert.NotNil(err, "expected an error for an earlier log without #close")
+}
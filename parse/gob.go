@@ -0,0 +1,127 @@
+package parse
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+func init() {
+	// The Go types of typed values sent as interface values
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register([]interface{}{})
+}
+
+// gobHeader is the first frame of a gob stream, holding its schema.
+type gobHeader struct {
+	Fields []string
+	Types  []string
+}
+
+// GobSink writes rows to w as a gob stream of typed rows, ex: to cache a
+// parsed Bro log for other processes, which read it back with GobReader
+// without parsing it again. The fields and types are written once, in a
+// header frame, and every row is converted to its Go values as TypedRow
+// does before being written.
+type GobSink struct {
+	enc     *gob.Encoder
+	types   []string
+	markers FieldMarkers
+}
+
+// NewGobSink returns a sink writing fields of the given Bro types to w,
+// ex: the fields and types returned by Schema.
+func NewGobSink(w io.Writer, fields, types []string) (*GobSink, error) {
+
+	if len(fields) != len(types) {
+		return nil, errors.New("Fields and types must be the same length")
+	}
+
+	s := &GobSink{enc: gob.NewEncoder(w), types: types, markers: DefaultFieldMarkers}
+	if err := s.enc.Encode(gobHeader{Fields: fields, Types: types}); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetFieldMarkers sets the unset, empty and set separator markers of the
+// rows written, ex: the ones returned by the FieldMarkers of their parser.
+// Rows are read with the DefaultFieldMarkers otherwise.
+func (s *GobSink) SetFieldMarkers(markers FieldMarkers) {
+	s.markers = markers.orDefault()
+}
+
+// Write converts a row to its Go values and writes it.
+func (s *GobSink) Write(row []string) error {
+
+	if len(row) != len(s.types) {
+		return errors.New("Row has " + strconv.Itoa(len(row)) + " values but there are " + strconv.Itoa(len(s.types)) + " types")
+	}
+
+	typed := make([]interface{}, len(row))
+	for i, value := range row {
+		var err error
+		typed[i], err = coerceWith(nil, s.markers, s.types[i], value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.enc.Encode(typed)
+}
+
+// Close does nothing, every row is written as it is received. The
+// underlying writer is not closed.
+func (s *GobSink) Close() error {
+	return nil
+}
+
+// GobReader reads the typed rows written by a GobSink.
+type GobReader struct {
+	dec    *gob.Decoder
+	header gobHeader
+}
+
+// NewGobReader returns a reader of the gob stream r, reading its header.
+func NewGobReader(r io.Reader) (*GobReader, error) {
+
+	g := &GobReader{dec: gob.NewDecoder(r)}
+	if err := g.dec.Decode(&g.header); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Fields returns the fields of the rows of the stream.
+func (g *GobReader) Fields() []string {
+	return g.header.Fields
+}
+
+// Types returns the Bro types of the fields of the stream.
+func (g *GobReader) Types() []string {
+	return g.header.Types
+}
+
+// Read returns the next typed row of the stream, with the Go values of
+// TypedRow. io.EOF is returned once every row is read.
+func (g *GobReader) Read() ([]interface{}, error) {
+
+	var row []interface{}
+	if err := g.dec.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	// gob decodes empty sets and vectors as nil slices
+	for i, value := range row {
+		if elements, ok := value.([]interface{}); ok && elements == nil {
+			row[i] = []interface{}{}
+		}
+	}
+
+	return row, nil
+}
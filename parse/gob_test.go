@@ -0,0 +1,59 @@
+package parse
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGobSink(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []string{"ts", "uid", "id.orig_p", "orig_bytes", "duration", "tunnel_parents"}
+	types := []string{"time", "string", "port", "count", "interval", "set[string]"}
+
+	var out bytes.Buffer
+	sink, err := NewGobSink(&out, fields, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := make(chan []string, 3)
+	rows <- []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "443", "100", "1.500000", "C1,C2"}
+	rows <- []string{"1452684904.908400", "-", "-", "-", "-", "(empty)"}
+	rows <- []string{"-", "-", "-", "-", "-", "-"}
+	close(rows)
+
+	err = Drain(rows, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewGobReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(reader.Fields(), fields, "read the fields of the header incorrectly")
+	assert.Equal(reader.Types(), types, "read the types of the header incorrectly")
+
+	row, err := reader.Read()
+	assert.Nil(err)
+	assert.Equal(row, []interface{}{time.Unix(1452684903, 908400000).UTC(), "CbOiIv2wbbH7F25W21", uint16(443), uint64(100), 1500 * time.Millisecond, []interface{}{"C1", "C2"}}, "decoded typed values incorrectly")
+
+	row, err = reader.Read()
+	assert.Nil(err)
+	assert.Equal(row, []interface{}{time.Unix(1452684904, 908400000).UTC(), nil, nil, nil, nil, []interface{}{}}, "unset values should be nil")
+
+	row, err = reader.Read()
+	assert.Nil(err)
+	assert.Equal(row, []interface{}{nil, nil, nil, nil, nil, nil}, "unset values should be nil")
+
+	_, err = reader.Read()
+	assert.Equal(err, io.EOF, "expected the end of the stream")
+
+	err = sink.Write([]string{"1452684903.908400"})
+	assert.NotNil(err, "expected an error for a row of the wrong length")
+}
@@ -0,0 +1,334 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultSeparator is used when a Bro log doesn't declare a #separator.
+const defaultSeparator = "\t"
+
+// FieldMarkers are the values a Bro log writes for unset and empty fields,
+// and between the elements of sets and vectors, as declared by its
+// #unset_field, #empty_field and #set_separator headers.
+type FieldMarkers struct {
+	Unset        string
+	Empty        string
+	SetSeparator string
+}
+
+// DefaultFieldMarkers are used for the markers a Bro log doesn't declare.
+var DefaultFieldMarkers = FieldMarkers{Unset: "-", Empty: "(empty)", SetSeparator: ","}
+
+// orDefault returns the markers with the ones that aren't declared set to
+// their default.
+func (m FieldMarkers) orDefault() FieldMarkers {
+	if m.Unset == "" {
+		m.Unset = DefaultFieldMarkers.Unset
+	}
+	if m.Empty == "" {
+		m.Empty = DefaultFieldMarkers.Empty
+	}
+	if m.SetSeparator == "" {
+		m.SetSeparator = DefaultFieldMarkers.SetSeparator
+	}
+	return m
+}
+
+// header holds the directives found at the top of a Bro log.
+type header struct {
+	separator string
+	markers   FieldMarkers
+	path      string
+	open      string
+	fields    []string
+	types     []string
+	json      bool
+	collapse  bool
+}
+
+// readHeader reads the header lines of the Bro log, stopping at the first
+// entry or as soon as both the #fields and #types lines are read. Zeek JSON
+// logs are read to the end, for the keys of every object. The
+// separator and field markers declared by the log are stored on the
+// parser so that entries are split and read the same way as the header.
+func (p *Parser) readHeader() (*header, error) {
+
+	file, fileErr := p.open()
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	defer file.Close()
+
+	h := p.newHeader()
+
+	scanner, _ := p.newScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			continue
+		}
+
+		// Zeek JSON logs have no header, it comes from the keys of every
+		// object, since unset fields are left out of them
+		if line[0] == '{' {
+			if err := p.parseJSONHeader(h, line); err != nil && !h.json {
+				return nil, err
+			}
+			continue
+		}
+
+		if h.json {
+			continue
+		}
+		if line[0] != '#' {
+			break
+		}
+
+		if err := h.parseLine(line); err != nil {
+			return nil, err
+		}
+
+		// The fields of a header file are replaced by the header of the log
+		// if it has one, so it is read up to its first entry
+		if p.sidecarHeader == nil && h.fields != nil && h.types != nil {
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if h.json {
+		p.jsonFields = h.fields
+	}
+
+	p.useHeader(h)
+	return h, nil
+}
+
+// useHeader stores the separator and field markers of a header on the
+// parser.
+func (p *Parser) useHeader(h *header) {
+	p.separator = h.separator
+	p.markers = h.markers.orDefault()
+}
+
+// newHeader returns the header a Bro log starts with before its own header
+// lines are read, either the one loaded by LoadHeaderFrom or an empty one.
+func (p *Parser) newHeader() *header {
+	if p.sidecarHeader != nil {
+		h := *p.sidecarHeader
+		h.collapse = p.collapseSeparators
+		return &h
+	}
+	return &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+}
+
+// LoadHeaderFrom reads the #separator, #fields and #types lines, and the
+// field markers, of a separate header file, and uses them for the Bro log.
+// This allows parsing headerless logs that share a single header file, ex: archives.
+// Header lines in the Bro log itself still take precedence.
+func (p *Parser) LoadHeaderFrom(path string) error {
+
+	var file io.ReadCloser
+	var err error
+	if p.fsys != nil {
+		file, err = p.fsys.Open(path)
+	} else {
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h := &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] != '#' {
+			break
+		}
+
+		if err := h.parseLine(line); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if h.fields == nil {
+		return errors.New("No fields header found")
+	}
+
+	p.sidecarHeader = h
+	return nil
+}
+
+// DetectSeparator returns the separator of the Bro log, decoded from its
+// #separator header (ex: "\x09" is a tab), reading only its header. Logs
+// without a #separator are separated by tabs. The separator is stored on
+// the parser, the way ParseAllFields and BufferRow store it too.
+func (p *Parser) DetectSeparator() (string, error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return "", err
+	}
+
+	return h.separator, nil
+}
+
+// FieldMarkers returns the unset, empty and set separator markers of the
+// Bro log, reading only its header, ex: to configure a sink with the
+// markers of the rows it is written. Markers the log doesn't declare are
+// the DefaultFieldMarkers.
+func (p *Parser) FieldMarkers() (FieldMarkers, error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return FieldMarkers{}, err
+	}
+
+	return h.markers.orDefault(), nil
+}
+
+// Schema returns the fields and types of the Bro log, reading only its
+// header. No entries are read.
+func (p *Parser) Schema() (fields, types []string, err error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if h.fields == nil {
+		return nil, nil, errors.New("No fields header found")
+	}
+
+	return h.fields, h.types, nil
+}
+
+// Path returns the type of the Bro log (ex: "conn", "dns"), read from its
+// #path header, or from the _path key of a Zeek JSON log.
+func (p *Parser) Path() (string, error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return "", err
+	}
+
+	if h.path == "" {
+		return "", errors.New("No path header found")
+	}
+
+	return h.path, nil
+}
+
+// parseLine parses a single header line. The #separator line is always
+// space delimited, every other directive uses the declared separator.
+func (h *header) parseLine(line string) error {
+
+	key, value := h.directive(line)
+
+	switch key {
+	case "separator":
+		separator, err := decodeSeparator(value)
+		if err != nil {
+			return err
+		}
+		h.separator = separator
+	case "set_separator":
+		separator, err := decodeSeparator(value)
+		if err != nil {
+			return err
+		}
+		h.markers.SetSeparator = separator
+	case "unset_field":
+		h.markers.Unset = value
+	case "empty_field":
+		h.markers.Empty = value
+	case "path":
+		h.path = value
+	case "open":
+		h.open = value
+	case "fields":
+		if value == "" {
+			return errors.New("Fields row is malformed")
+		}
+		h.fields = splitValues(value, h.separator, h.collapse)
+	case "types":
+		if value == "" {
+			return errors.New("Types row is malformed")
+		}
+		h.types = splitValues(value, h.separator, h.collapse)
+	}
+
+	return nil
+}
+
+// directive splits a header line into its key (ex: "fields") and its value,
+// as written in the Bro log.
+func (h *header) directive(line string) (key, value string) {
+
+	// The separator can't be split on before it is known
+	if strings.HasPrefix(line, "#separator ") {
+		return "separator", line[len("#separator "):]
+	}
+
+	parts := strings.SplitN(line[1:], h.separator, 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+// SetHeaderCallback sets a function called with the key and value of every
+// header line (ex: "open", "2016-01-13-11-35-03") as it is read while
+// parsing entries, ex: to partition output by #open without another pass.
+// Values are as written in the Bro log, still joined by the separator.
+func (p *Parser) SetHeaderCallback(callback func(key, value string)) {
+	p.headerCallback = callback
+}
+
+// decodeSeparator decodes the \xNN escapes Bro uses to write its separator,
+// ex: "\x09" is decoded to a tab. Separators of several bytes are written
+// as an escape per byte (ex: "\x7c\x7c"), or as they are.
+func decodeSeparator(s string) (string, error) {
+
+	var decoded strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			b, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			if err != nil {
+				return "", errors.New("Separator is malformed: " + s)
+			}
+			decoded.WriteByte(byte(b))
+			i += 3
+			continue
+		}
+		decoded.WriteByte(s[i])
+	}
+
+	if decoded.Len() == 0 {
+		return "", errors.New("Separator is empty")
+	}
+
+	return decoded.String(), nil
+}
@@ -0,0 +1,391 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeclaredSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/space.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := parser.readHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(h.separator, " ", "decoded separator incorrectly")
+	assert.Equal(h.types, []string{"time", "string", "addr", "port", "addr", "port", "enum"}, "parsed types incorrectly")
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto"}, "parsed fields incorrectly")
+
+	parser.SetFields(fields)
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+
+	assert.Equal(len(rows), 2, "parsed wrong number of entries")
+	assert.Equal(rows[0][2], "10.1.20.227", "parsed entries incorrectly")
+	assert.Equal(rows[1][6], "udp", "parsed entries incorrectly")
+}
+
+func TestDetectSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"pipe.log":       {Data: []byte("#separator \\x7c\n#fields|ts|uid\n1452684903.908400|CbOiIv2wbbH7F25W21\n")},
+		"undeclared.log": {Data: []byte("#fields\tts\tuid\n1452684903.908400\tCbOiIv2wbbH7F25W21\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "pipe.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separator, err := parser.DetectSeparator()
+	assert.Nil(err)
+	assert.Equal(separator, "|", "decoded separator incorrectly")
+	assert.Equal(parser.separator, "|", "expected the separator stored on the parser")
+
+	parser, err = NewParserFS(fsys, "undeclared.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separator, err = parser.DetectSeparator()
+	assert.Nil(err)
+	assert.Equal(separator, "\t", "expected a tab when no separator is declared")
+}
+
+func TestCollapseSeparators(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"space.log": {Data: []byte("#separator \\x20\n" +
+			"#fields ts   uid  proto\n" +
+			"1452684903.908400   CbOiIv2wbbH7F25W21 tcp\n" +
+			"  1452684904.918400 C7fIlMZDuRiqjpYbb    udp  \n")},
+		"tab.log": {Data: []byte("#separator \\x09\n" +
+			"#fields\tts\tuid\tproto\n" +
+			"1452684903.908400\t\ttcp\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "space.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetCollapseSeparators(true)
+
+	fields, err := parser.ParseAllFields()
+	assert.Nil(err)
+	assert.Equal(fields, []string{"ts", "uid", "proto"}, "runs of spaces in the header should be collapsed")
+
+	parser.SetFields(fields)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp"},
+		{"1452684904.918400", "C7fIlMZDuRiqjpYbb", "udp"},
+	}, "runs of spaces in entries should be collapsed")
+
+	// Empty values between tabs are kept
+	parser, err = NewParserFS(fsys, "tab.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetCollapseSeparators(true)
+	fields, err = parser.ParseAllFields()
+	assert.Nil(err)
+	parser.SetFields(fields)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"1452684903.908400", "", "tcp"}}, "tab separators should not be collapsed")
+}
+
+func TestDecodeSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	separator, err := decodeSeparator("\\x09")
+	assert.Nil(err)
+	assert.Equal(separator, "\t", "decoded separator incorrectly")
+
+	separator, err = decodeSeparator("\\x7c\\x7c")
+	assert.Nil(err)
+	assert.Equal(separator, "||", "decoded separator incorrectly")
+
+	_, err = decodeSeparator("\\xzz")
+	assert.NotNil(err, "expected an error for a malformed separator")
+}
+
+func TestMultiByteSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, declared := range []string{"\\x7c\\x7c", "||"} {
+		fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator " + declared + "\n" +
+			"#path||conn\n" +
+			"#fields||ts||uid||history\n" +
+			"#types||time||string||string\n" +
+			"1452684903.908400||CbOiIv2wbbH7F25W21||S|h\n" +
+			"1452684905.100000||C7fIlMZDuRiqjpYbb||\n")}}
+
+		parser, err := NewParserFS(fsys, "conn.log", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fields, types, err := parser.Schema()
+		assert.Nil(err)
+		assert.Equal(fields, []string{"ts", "uid", "history"}, "parsed fields incorrectly")
+		assert.Equal(types, []string{"time", "string", "string"}, "parsed types incorrectly")
+
+		path, err := parser.Path()
+		assert.Nil(err)
+		assert.Equal(path, "conn", "parsed path incorrectly")
+
+		parser.SetFields(fields)
+		rows, err := parser.ReadAll()
+		assert.Nil(err)
+		assert.Equal(rows, [][]string{
+			{"1452684903.908400", "CbOiIv2wbbH7F25W21", "S|h"},
+			{"1452684905.100000", "C7fIlMZDuRiqjpYbb", ""},
+		}, "parsed entries incorrectly")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/space.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, types, err := parser.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields[2], "id.orig_h", "parsed fields incorrectly")
+	assert.Equal(types[2], "addr", "parsed types incorrectly")
+
+	// Lines after the header must not be read, even if they can't be parsed
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#fields\tts\n#types\ttime\n#fields\t\n")}}
+	parser, err = NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, types, err = parser.Schema()
+	assert.Nil(err)
+	assert.Equal(fields, []string{"ts"}, "parsed fields incorrectly")
+	assert.Equal(types, []string{"time"}, "parsed types incorrectly")
+
+	fsys = fstest.MapFS{"conn.log": {Data: []byte("1452684903.908400\ttcp\n")}}
+	parser, err = NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = parser.Schema()
+	assert.NotNil(err, "expected an error for a log without a #fields header")
+}
+
+func TestLoadHeaderFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"conn.header": {Data: []byte("#separator \\x2c\n#fields,ts,uid,proto\n#types,time,string,enum\n")},
+		"conn.csv":    {Data: []byte("1452684903.908400,CbOiIv2wbbH7F25W21,tcp\n1452684904.100000,C7fIlMZDuRiqjpYbb,udp\n")},
+		"empty":       {Data: []byte("")},
+		"own.log":     {Data: []byte("#separator \\x09\n#fields\tuid\tproto\n#types\tstring\tenum\nCbOiIv2wbbH7F25W21\ttcp\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "conn.csv", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = parser.LoadHeaderFrom("conn.header")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, types, err := parser.Schema()
+	assert.Nil(err)
+	assert.Equal(fields, []string{"ts", "uid", "proto"}, "expected the fields of the header file")
+	assert.Equal(types, []string{"time", "string", "enum"}, "expected the types of the header file")
+
+	parser.SetFields([]string{"proto", "uid"})
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"tcp", "CbOiIv2wbbH7F25W21"}, {"udp", "C7fIlMZDuRiqjpYbb"}}, "expected entries split with the header file")
+
+	// The header lines of the log itself take precedence
+	parser, err = NewParserFS(fsys, "own.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.LoadHeaderFrom("conn.header"); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, types, err = parser.Schema()
+	assert.Nil(err)
+	assert.Equal(fields, []string{"uid", "proto"}, "expected the fields of the log")
+	assert.Equal(types, []string{"string", "enum"}, "expected the types of the log")
+
+	parser.SetFields(fields)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"CbOiIv2wbbH7F25W21", "tcp"}}, "expected entries split with the header of the log")
+
+	assert.NotNil(parser.LoadHeaderFrom("empty"), "expected an error for a header file without fields")
+	assert.NotNil(parser.LoadHeaderFrom("missing"), "expected an error for a missing header file")
+}
+
+func TestHeaderCallback(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	directives := make(map[string]string)
+	var keys []string
+	parser.SetHeaderCallback(func(key, value string) {
+		keys = append(keys, key)
+		directives[key] = value
+	})
+
+	_, err = parser.ReadAll()
+	assert.Nil(err)
+
+	assert.Equal(keys[:6], []string{"separator", "set_separator", "empty_field", "unset_field", "path", "open"}, "expected header lines in order")
+	assert.Equal(directives["separator"], "\\x09", "expected the separator as written")
+	assert.Equal(directives["path"], "conn", "expected the #path value")
+	assert.Equal(directives["open"], "2016-01-13-06-41-02", "expected the #open value")
+}
+
+func TestRepeatedHeaderBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	first := "#separator \\x09\n#path\tconn\n#fields\tts\tuid\tproto\n#types\ttime\tstring\tenum\n" +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n#close\t2016-01-13-07-00-00\n"
+	reordered := "#separator \\x09\n#path\tconn\n#fields\tuid\tproto\tts\n#types\tstring\tenum\ttime\n" +
+		"C7fIlMZDuRiqjpYbb\tudp\t1452684904.908400\n#close\t2016-01-13-08-00-00\n"
+
+	fsys := fstest.MapFS{
+		"same.log":      {Data: []byte(first + first)},
+		"reordered.log": {Data: []byte(first + reordered)},
+	}
+
+	parser, err := NewParserFS(fsys, "same.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 2, "expected the entries of both header blocks")
+
+	parser, err = NewParserFS(fsys, "reordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"CbOiIv2wbbH7F25W21", "tcp"}, {"C7fIlMZDuRiqjpYbb", "udp"}}, "fields should be looked up again in each header block")
+
+	parser, err = NewParserFS(fsys, "reordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+	parser.CreateErrorBuffer(10)
+
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 1, "entries of a header block with different fields should be skipped")
+	assert.Equal(len(parser.Errors), 1, "expected the different header block to be reported")
+
+	// A shard starting after the first header block checks the blocks in it
+	// against the header read first
+	parser, err = NewParserFS(fsys, "reordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+	parser.CreateErrorBuffer(10)
+	parser.SetByteRange(int64(len(first)), 0)
+
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Nil(rows, "entries of a header block with different fields in a shard should be skipped")
+	assert.Equal(len(parser.Errors), 1, "expected the different header block in the shard to be reported")
+}
+
+func TestFieldMarkers(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"markers.log": {Data: []byte("#separator \\x09\n#set_separator\t;\n#empty_field\tEMPTY\n#unset_field\tNULL\n" +
+			"#fields\tuid\tservice\ttunnel_parents\n#types\tstring\tset[string]\tset[string]\n" +
+			"C1\tssl;http\tEMPTY\nC2\tNULL\tC1\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "markers.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	markers, err := parser.FieldMarkers()
+	assert.Nil(err)
+	assert.Equal(markers, FieldMarkers{Unset: "NULL", Empty: "EMPTY", SetSeparator: ";"}, "parsed markers incorrectly")
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetSortContainers(true)
+	parser.SetUnsetOutput("")
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"C1", "http;ssl", "EMPTY"}, {"C2", "", "C1"}}, "expected sets split and unset values replaced by the declared markers")
+
+	typed, err := parser.TypedRow([]string{"C1", "http;ssl", "EMPTY"})
+	assert.Nil(err)
+	assert.Equal(typed, []interface{}{"C1", []interface{}{"http", "ssl"}, []interface{}{}}, "expected sets split on the declared set separator")
+
+	typed, err = parser.TypedRow([]string{"C2", "NULL", "C1"})
+	assert.Nil(err)
+	assert.Nil(typed[1], "expected the declared unset value to be nil")
+
+	defaults, err := NewParser("../sample_logs/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markers, err = defaults.FieldMarkers()
+	assert.Nil(err)
+	assert.Equal(markers, DefaultFieldMarkers, "expected the default markers when none are declared")
+}
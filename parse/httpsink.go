@@ -0,0 +1,291 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTPSink buffers rows as newline delimited JSON (NDJSON) and POSTs
+// them in batches to a URL, ex: the HTTP ingestion endpoint of a SIEM.
+// A batch is sent once it holds batchSize rows, or once flushInterval
+// has passed since its first row was buffered.
+type HTTPSink struct {
+	url           string
+	fields        []string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	retries       int
+	backoff       time.Duration
+	omitUnset     bool
+	markers       FieldMarkers
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	pending int
+	timer   *time.Timer
+	queue   [][]byte
+	err     error
+
+	// sendMu is held while posting the queued batches
+	sendMu sync.Mutex
+}
+
+// NewHTTPSink returns a sink posting to url, using fields as the keys
+// of each JSON object. By default batches hold 500 rows, are flushed
+// every 5 seconds and failed posts are retried 3 times.
+func NewHTTPSink(url string, fields []string) *HTTPSink {
+	return &HTTPSink{
+		url:           url,
+		fields:        fields,
+		client:        http.DefaultClient,
+		batchSize:     500,
+		flushInterval: 5 * time.Second,
+		retries:       3,
+		backoff:       time.Second,
+		markers:       DefaultFieldMarkers,
+	}
+}
+
+// SetClient sets the http client used to post batches.
+func (s *HTTPSink) SetClient(client *http.Client) {
+	s.client = client
+}
+
+// SetBatchSize sets the number of rows sent in a single post.
+func (s *HTTPSink) SetBatchSize(size int) {
+	s.batchSize = size
+}
+
+// SetFlushInterval sets how long a partial batch waits before it is sent.
+// An interval of 0 disables time based flushing.
+func (s *HTTPSink) SetFlushInterval(interval time.Duration) {
+	s.flushInterval = interval
+}
+
+// SetRetries sets how many times a failed post is retried. The wait
+// between attempts starts at backoff and doubles after every attempt.
+func (s *HTTPSink) SetRetries(retries int, backoff time.Duration) {
+	s.retries = retries
+	s.backoff = backoff
+}
+
+// SetOmitUnset sets whether unset fields are left out of the JSON objects
+// instead of being written with the unset value, the way Zeek writes its
+// JSON logs.
+func (s *HTTPSink) SetOmitUnset(enabled bool) {
+	s.omitUnset = enabled
+}
+
+// SetFieldMarkers sets the markers of the rows written, ex: the ones
+// returned by the FieldMarkers of their parser, so that unset fields are
+// recognized in logs declaring another #unset_field than "-".
+func (s *HTTPSink) SetFieldMarkers(markers FieldMarkers) {
+	s.markers = markers.orDefault()
+}
+
+// Write buffers a row, and posts the batch if it is full. Errors from
+// a previous time based flush are returned here. Rows can be written while
+// a batch is being posted or retried.
+func (s *HTTPSink) Write(row []string) error {
+
+	full, err := s.buffer(row)
+	if err != nil || !full {
+		return err
+	}
+
+	return s.send()
+}
+
+// buffer encodes a row into the current batch, and reports whether the
+// batch is full and queued to be sent.
+func (s *HTTPSink) buffer(row []string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return false, s.err
+	}
+
+	fields := s.fields
+	if s.omitUnset {
+		fields, row = withoutUnset(fields, row, s.markers.Unset)
+	}
+	if err := writeJSONObject(&s.batch, fields, row); err != nil {
+		return false, err
+	}
+	s.pending++
+
+	if s.pending >= s.batchSize {
+		s.queueBatch()
+		return true, nil
+	}
+
+	if s.timer == nil && s.flushInterval > 0 {
+		s.timer = time.AfterFunc(s.flushInterval, func() {
+			if err := s.Flush(); err != nil {
+				s.mu.Lock()
+				if s.err == nil {
+					s.err = err
+				}
+				s.mu.Unlock()
+			}
+		})
+	}
+
+	return false, nil
+}
+
+// Flush posts any buffered rows.
+func (s *HTTPSink) Flush() error {
+	s.mu.Lock()
+	s.queueBatch()
+	s.mu.Unlock()
+
+	return s.send()
+}
+
+// Close posts any buffered rows. The sink should not be written to afterwards.
+func (s *HTTPSink) Close() error {
+
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// queueBatch moves the current batch to the queue of batches to send, the
+// caller must hold s.mu.
+func (s *HTTPSink) queueBatch() {
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	if s.pending == 0 {
+		return
+	}
+
+	body := make([]byte, s.batch.Len())
+	copy(body, s.batch.Bytes())
+	s.queue = append(s.queue, body)
+	s.batch.Reset()
+	s.pending = 0
+}
+
+// send posts the queued batches in order, and returns the first error. It
+// doesn't hold s.mu while posting, so that rows are still buffered during
+// retries, and only one caller sends at a time so batches aren't reordered.
+func (s *HTTPSink) send() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	var firstErr error
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return firstErr
+		}
+		body := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		if err := s.sendBatch(body); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+}
+
+// sendBatch posts a single batch, retrying failures worth retrying.
+func (s *HTTPSink) sendBatch(body []byte) error {
+
+	backoff := s.backoff
+	var err error
+
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = s.post(body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// post sends a single batch, and reports whether a failure is worth retrying.
+// Connection errors, 429s and 5xx responses are retried.
+func (s *HTTPSink) post(body []byte) (bool, error) {
+
+	resp, err := s.client.Post(s.url, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = errors.New("HTTP sink received status " + strconv.Itoa(resp.StatusCode) + " from " + s.url)
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, err
+}
+
+// writeJSONObject writes a row as a single line JSON object, keeping the
+// order of the fields.
+func writeJSONObject(buf *bytes.Buffer, fields, row []string) error {
+
+	if len(fields) != len(row) {
+		return errors.New("Row has " + strconv.Itoa(len(row)) + " values but there are " + strconv.Itoa(len(fields)) + " fields")
+	}
+
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		value, _ := json.Marshal(row[i])
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	return nil
+}
+
+// withoutUnset returns the fields and values of a row without the fields
+// whose value is unset.
+func withoutUnset(fields, row []string, unset string) ([]string, []string) {
+
+	if len(fields) != len(row) {
+		return fields, row
+	}
+
+	var setFields, setValues []string
+	for i, value := range row {
+		if value != unset {
+			setFields = append(setFields, fields[i])
+			setValues = append(setValues, value)
+		}
+	}
+
+	return setFields, setValues
+}
@@ -0,0 +1,170 @@
+package parse
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ndjsonServer records the objects of every batch it receives, failing
+// the first failures requests with a 503.
+func ndjsonServer(failures int) (*httptest.Server, func() [][]map[string]string) {
+	var mu sync.Mutex
+	var batches [][]map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var batch []map[string]string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var object map[string]string
+			json.Unmarshal(scanner.Bytes(), &object)
+			batch = append(batch, object)
+		}
+		batches = append(batches, batch)
+	}))
+
+	return server, func() [][]map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+}
+
+func TestHTTPSinkBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	server, batches := ndjsonServer(1)
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, []string{"id.orig_h", "proto"})
+	sink.SetBatchSize(2)
+	sink.SetRetries(2, time.Millisecond)
+
+	rows := make(chan []string, 3)
+	rows <- []string{"10.1.20.227", "tcp"}
+	rows <- []string{"10.1.20.228", "udp"}
+	rows <- []string{"10.1.20.229", "icmp"}
+	close(rows)
+
+	err := Drain(rows, sink)
+	assert.Nil(err)
+
+	sent := batches()
+	assert.Equal(len(sent), 2, "rows should be sent in two batches")
+	assert.Equal(len(sent[0]), 2, "first batch should be full")
+	assert.Equal(sent[0][1]["proto"], "udp", "row was encoded incorrectly")
+	assert.Equal(sent[1][0]["id.orig_h"], "10.1.20.229", "partial batch should be flushed on close")
+}
+
+func TestHTTPSinkFlushInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	server, batches := ndjsonServer(0)
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, []string{"proto"})
+	sink.SetFlushInterval(10 * time.Millisecond)
+
+	assert.Nil(sink.Write([]string{"tcp"}))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(len(batches()), 1, "partial batch should be flushed after the interval")
+	assert.Nil(sink.Close())
+}
+
+func TestHTTPSinkWriteDuringPost(t *testing.T) {
+	assert := assert.New(t)
+
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server, batches := ndjsonServer(0)
+	defer server.Close()
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the first post is held until it is released
+		select {
+		case received <- struct{}{}:
+			<-release
+		default:
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer blocking.Close()
+
+	sink := NewHTTPSink(blocking.URL, []string{"proto"})
+	sink.SetBatchSize(2)
+	sink.SetFlushInterval(0)
+
+	posted := make(chan error)
+	go func() {
+		if err := sink.Write([]string{"tcp"}); err != nil {
+			posted <- err
+			return
+		}
+		posted <- sink.Write([]string{"udp"})
+	}()
+
+	<-received
+	written := make(chan error)
+	go func() { written <- sink.Write([]string{"icmp"}) }()
+
+	select {
+	case err := <-written:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("Write was blocked by a batch being posted")
+	}
+
+	close(release)
+	assert.Nil(<-posted)
+	assert.Nil(sink.Close())
+
+	sent := batches()
+	assert.Equal(len(sent), 2, "rows should be sent in two batches")
+	assert.Equal(sent[1][0]["proto"], "icmp", "batches should be sent in order")
+}
+
+func TestHTTPSinkError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, []string{"proto"})
+	sink.SetRetries(3, time.Hour)
+
+	assert.Nil(sink.Write([]string{"tcp"}))
+	assert.NotNil(sink.Close(), "client errors should be surfaced without retrying")
+}
+
+func TestHTTPSinkOmitUnset(t *testing.T) {
+	assert := assert.New(t)
+
+	server, batches := ndjsonServer(0)
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, []string{"id.orig_h", "service"})
+	sink.SetOmitUnset(true)
+
+	assert.Nil(sink.Write([]string{"10.1.20.227", "-"}))
+	assert.Nil(sink.Close())
+
+	assert.Equal(batches()[0][0], map[string]string{"id.orig_h": "10.1.20.227"}, "expected the unset field to be omitted")
+}
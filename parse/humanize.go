@@ -0,0 +1,83 @@
+package parse
+
+import (
+	"strings"
+)
+
+// humanizedSuffixes are the number of zeros of the unit suffixes of
+// humanized numbers.
+var humanizedSuffixes = map[byte]int{
+	'K': 3, 'k': 3,
+	'M': 6,
+	'G': 9,
+}
+
+// SetHumanizedNumbers enables or disables converting count, int and double
+// values that were humanized by an export, ex: "1,234" or "1.2K". Thousands
+// separators are removed and K, M and G suffixes are expanded before the
+// value is converted, so that "1.2K" is a count of 1200. It is off by
+// default, since a humanized value isn't a valid Bro value.
+func (p *Parser) SetHumanizedNumbers(enabled bool) {
+	p.humanizedNumbers = enabled
+}
+
+// isNumericType reports whether a Bro type may be humanized.
+func isNumericType(broType string) bool {
+	return broType == "count" || broType == "int" || broType == "double"
+}
+
+// dehumanize returns a humanized number as a plain decimal number, or value
+// as it is if it isn't one.
+func dehumanize(value string) string {
+
+	number, zeros := value, 0
+	if n := len(number); n > 1 {
+		if z, ok := humanizedSuffixes[number[n-1]]; ok {
+			number, zeros = number[:n-1], z
+		}
+	}
+
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = number[:1], number[1:]
+	}
+
+	whole, fraction := number, ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		whole, fraction = number[:i], number[i+1:]
+	}
+
+	// Thousands separators must group digits by 3
+	if strings.IndexByte(whole, ',') >= 0 {
+		groups := strings.Split(whole, ",")
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return value
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return value
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+
+	// The suffix moves the decimal point right
+	if zeros > 0 {
+		if len(fraction) < zeros {
+			fraction += strings.Repeat("0", zeros-len(fraction))
+		}
+		whole, fraction = whole+fraction[:zeros], fraction[zeros:]
+		whole = strings.TrimLeft(whole, "0")
+		if whole == "" {
+			whole = "0"
+		}
+		if strings.Trim(fraction, "0") == "" {
+			fraction = ""
+		}
+	}
+
+	if fraction != "" {
+		return sign + whole + "." + fraction
+	}
+	return sign + whole
+}
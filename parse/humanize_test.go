@@ -0,0 +1,58 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDehumanize(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expected := range map[string]string{
+		"1,234":       "1234",
+		"1,234,567.5": "1234567.5",
+		"1.2K":        "1200",
+		"1.23456K":    "1234.56",
+		"0.5M":        "500000",
+		"-2G":         "-2000000000",
+		"12":          "12",
+		"-":           "-",
+		"1,23":        "1,23",
+		"1234,567":    "1234,567",
+	} {
+		assert.Equal(dehumanize(value), expected, "dehumanized "+value+" incorrectly")
+	}
+}
+
+func TestSetHumanizedNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\torig_bytes\tduration_ms\n" +
+		"#types\tcount\tdouble\n" +
+		"1,234\t1.5K\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	row := []string{"1,234", "1.5K"}
+	_, err = parser.TypedRow(row)
+	assert.NotNil(err, "expected humanized numbers to be invalid by default")
+
+	parser.SetHumanizedNumbers(true)
+	typed, err := parser.TypedRow(row)
+	assert.Nil(err)
+	assert.Equal(typed, []interface{}{uint64(1234), float64(1500)}, "converted humanized numbers incorrectly")
+
+	_, err = parser.TypedRow([]string{"1.5", "-"})
+	assert.NotNil(err, "expected a fractional count to be invalid")
+}
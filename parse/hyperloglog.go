@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits used to select a register.
+// 2^14 registers use 16KB of memory and give a standard error of
+// roughly 1.04/sqrt(2^14), or 0.81%.
+const hllPrecision = 14
+
+// hyperLogLog estimates the number of distinct values added to it,
+// using a fixed amount of memory regardless of the number of values.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+// add records a single value.
+func (h *hyperLogLog) add(value string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	hash := mix64(hasher.Sum64())
+
+	index := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// count returns the estimated number of distinct values.
+func (h *hyperLogLog) count() int {
+	m := float64(len(h.registers))
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	var sum float64
+	var zeros int
+	for _, register := range h.registers {
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+
+	// Small cardinalities are more accurately estimated by linear counting
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int(estimate + 0.5)
+}
+
+// mix64 is the splitmix64 finalizer, it spreads the bits of FNV hashes
+// which are otherwise too weak in the high bits used to pick a register.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
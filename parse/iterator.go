@@ -0,0 +1,145 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"time"
+)
+
+// errStopEmitting ends a parse early, once no more rows should be emitted.
+var errStopEmitting = errors.New("Stop emitting rows")
+
+// SetFilter sets a predicate rows must match to be emitted, before any
+// Parse() functions are applied. A nil pred emits every row.
+func (p *Parser) SetFilter(pred func([]string) bool) {
+	p.filter = pred
+}
+
+// SetOffset skips the first offset rows matching the filter.
+func (p *Parser) SetOffset(offset int) {
+	p.offset = offset
+}
+
+// SetLimit stops parsing once limit rows are emitted. A limit of 0, the
+// default, means no limit.
+func (p *Parser) SetLimit(limit int) {
+	p.limit = limit
+}
+
+// eachSelectedRow is eachRow, with only the rows selected by the
+// blocklists, filter, offset and limit, with their control bytes handled,
+// their field transforms applied, their invalid values tolerated and their
+// sets sorted. It is shared by every way of emitting rows, whether they are
+// pushed (ex: BufferRow) or pulled (Iterate).
+func (p *Parser) eachSelectedRow(fn func(int, []string) error) error {
+
+	var skipped, emitted int
+	defer p.metrics.observeDuration(time.Now())
+	defer p.startProfile()()
+
+	// Blocklists are resolved in the fields parsed, as eachRow orders them
+	p.applyFieldOrder()
+	blocklists, err := p.resolveBlocklists()
+	if err != nil {
+		return err
+	}
+	setFields, err := p.resolveSetFields()
+	if err != nil {
+		return err
+	}
+	tolerances, err := p.resolveTolerances()
+	if err != nil {
+		return err
+	}
+	summary, err := p.startSummary()
+	if err != nil {
+		return err
+	}
+	defer summary.finish(p)
+
+	err = p.eachRow(func(lineNum int, row []string) error {
+		defer p.profile.add(profileSelect, p.profile.start())
+		row, ok := p.handleControlBytes(lineNum, row)
+		if !ok {
+			return nil
+		}
+		row, err := p.transformFields(lineNum, row)
+		if err != nil {
+			return err
+		}
+		row, err = tolerances.checkRow(p, row)
+		if err != nil {
+			return err
+		}
+		row = sortSets(setFields, row, p.markers.SetSeparator)
+		if isBlocked(blocklists, row) {
+			return nil
+		}
+		if p.filter != nil && !p.filter(row) {
+			return nil
+		}
+		if skipped < p.offset {
+			skipped++
+			return nil
+		}
+		emitted++
+		summary.observe(row)
+		p.metrics.addRow()
+		if err := p.profile.emit(fn, lineNum, row); err != nil {
+			return err
+		}
+
+		// Stopping at the last row, not the one after it, ends followed logs
+		if p.limit > 0 && emitted >= p.limit {
+			return errStopEmitting
+		}
+		return nil
+	})
+	if err == errStopEmitting || err == nil {
+		return tolerances.exceeded()
+	}
+	return err
+}
+
+// RowIterator pulls rows from a Bro log one at a time, see Iterate.
+type RowIterator struct {
+	next func() ([]string, error, bool)
+	stop func()
+}
+
+// Iterate returns an iterator over the rows of the Bro log, for consumers
+// pulling rows instead of reading them from p.Row. Rows are the same as
+// the ones BufferRow pushes: the filter, offset and limit apply, and so do
+// the Parse() functions given. The iterator should be closed when done.
+func (p *Parser) Iterate(parseFunc ...Parse) *RowIterator {
+
+	rows := func(yield func([]string, error) bool) {
+		err := p.eachSelectedRow(func(lineNum int, row []string) error {
+			if !yield(p.applyParse(row, parseFunc), nil) {
+				return errStopEmitting
+			}
+			return nil
+		})
+		if err != nil && err != errStopEmitting {
+			yield(nil, err)
+		}
+	}
+
+	next, stop := iter.Pull2(rows)
+	return &RowIterator{next: next, stop: stop}
+}
+
+// Next returns the next row, or io.EOF once there are no more rows.
+func (it *RowIterator) Next() ([]string, error) {
+	row, err, ok := it.next()
+	if !ok {
+		return nil, io.EOF
+	}
+	return row, err
+}
+
+// Close stops the iterator, and closes the Bro log if rows are left.
+func (it *RowIterator) Close() {
+	it.stop()
+}
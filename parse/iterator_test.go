@@ -0,0 +1,70 @@
+package parse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterate(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+
+	upper := func(fields, row []string) ([]string, error) {
+		return []string{row[0], strings.ToUpper(row[1])}, nil
+	}
+	suffix := func(fields, row []string) ([]string, error) {
+		return []string{row[0], row[1] + "!"}, nil
+	}
+
+	rows, err := parser.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iterator := parser.Iterate(upper, suffix)
+	var pulled [][]string
+	for {
+		row, err := iterator.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		pulled = append(pulled, row)
+	}
+	iterator.Close()
+
+	assert.Equal(len(pulled), len(rows), "expected every row to be pulled")
+	assert.Equal(pulled[0], []string{rows[0][0], "TCP!"}, "expected the Parse() functions to be chained")
+
+	// Filters, offsets and limits apply to pushed and pulled rows alike
+	parser.SetFilter(func(row []string) bool { return row[1] != "icmp" })
+	parser.SetOffset(1)
+	parser.SetLimit(1)
+
+	parser.CreateBuffer(10)
+	go parser.BufferRow()
+
+	var pushed [][]string
+	for row := range parser.Row {
+		pushed = append(pushed, row)
+	}
+
+	iterator = parser.Iterate()
+	defer iterator.Close()
+
+	row, err := iterator.Next()
+	assert.Nil(err)
+	assert.Equal([][]string{row}, pushed, "pulled rows should be the same as pushed rows")
+	assert.Equal(row, rows[1], "expected the second row matching the filter")
+
+	_, err = iterator.Next()
+	assert.Equal(err, io.EOF, "expected the limit to end the iterator")
+}
@@ -0,0 +1,214 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Zeek JSON logs have no # header, every line is a JSON object. Zeek leaves
+// unset fields out of an object, so fields are taken from the keys of every
+// object, in the order they first appear, and metadata such as the log type
+// is carried in "_" prefixed keys (ex: _path, _write_ts).
+
+// SetKeepMetaKeys sets which "_" prefixed metadata keys of a Zeek JSON log
+// are kept as fields. By default metadata keys are dropped from the entries,
+// though _path is always used to identify the log type.
+func (p *Parser) SetKeepMetaKeys(keys ...string) {
+	p.keepMetaKeys = make(map[string]bool)
+	for _, key := range keys {
+		p.keepMetaKeys[key] = true
+	}
+}
+
+// parseJSONHeader adds the keys of an object of a Zeek JSON log that aren't
+// fields yet to the header, and takes the log type from the first _path.
+func (p *Parser) parseJSONHeader(h *header, line string) error {
+
+	keys, values, err := decodeJSONObject(line)
+	if err != nil {
+		return err
+	}
+
+	if !h.json {
+		h.fields = nil
+	}
+	for i, key := range keys {
+		if key == "_path" && h.path == "" {
+			h.path = values[i]
+		}
+		if p.isDroppedMetaKey(key) || containsField(h.fields, key) {
+			continue
+		}
+		h.fields = append(h.fields, key)
+	}
+
+	h.json = true
+	return nil
+}
+
+// jsonEntry converts a Zeek JSON object into an entry, with its values in the
+// order of the header fields. Fields missing from the object are unset, which
+// is how Zeek writes unset fields in JSON. The fields are the ones found when
+// the header was read, and keys that weren't, ex: in objects written to a
+// followed log since, are reported.
+func (p *Parser) jsonEntry(h *header, lineNum int, line string) ([]string, error) {
+
+	if !h.json {
+		h.fields = append([]string(nil), p.jsonFields...)
+		h.json = p.jsonFields != nil
+		if err := p.parseJSONHeader(h, line); err != nil {
+			return nil, err
+		}
+	}
+
+	keys, values, err := decodeJSONObject(line)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]string, len(keys))
+	fieldKeys := 0
+	for i, key := range keys {
+		byKey[key] = values[i]
+		if !p.isDroppedMetaKey(key) {
+			fieldKeys++
+		}
+	}
+
+	entry := make([]string, len(h.fields))
+	found := 0
+	for i, field := range h.fields {
+		value, ok := byKey[field]
+		if !ok {
+			value = "-"
+		} else {
+			found++
+		}
+		entry[i] = value
+	}
+
+	if found < fieldKeys {
+		for _, key := range keys {
+			if !p.isDroppedMetaKey(key) && !containsField(h.fields, key) {
+				p.reportError(errors.New("Entry on line " + strconv.Itoa(lineNum) + " has key " + key + " that is not a field, its value is dropped"))
+			}
+		}
+	}
+
+	return entry, nil
+}
+
+// containsField reports whether field is one of fields.
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// isDroppedMetaKey reports whether key is metadata that should not be a field.
+func (p *Parser) isDroppedMetaKey(key string) bool {
+	return strings.HasPrefix(key, "_") && !p.keepMetaKeys[key]
+}
+
+// decodeJSONObject decodes a single JSON object into its keys and values,
+// keeping the order the keys were written in. Values are converted to the
+// text Bro would have written: nested objects are flattened into dotted
+// keys, arrays are joined with "," and booleans become T or F.
+func decodeJSONObject(line string) ([]string, []string, error) {
+
+	var keys, values []string
+	if err := flattenJSONObject([]byte(line), "", &keys, &values); err != nil {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
+
+func flattenJSONObject(data []byte, prefix string, keys, values *[]string) error {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return errors.New("JSON entry is not an object")
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := prefix + token.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		if len(raw) > 0 && raw[0] == '{' {
+			if err := flattenJSONObject(raw, key+".", keys, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := jsonValue(raw)
+		if err != nil {
+			return err
+		}
+		*keys = append(*keys, key)
+		*values = append(*values, value)
+	}
+
+	return nil
+}
+
+// jsonValue converts a JSON value that is not an object into text.
+func jsonValue(raw json.RawMessage) (string, error) {
+
+	if len(raw) == 0 {
+		return "-", nil
+	}
+
+	switch raw[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return "", err
+		}
+		if len(elements) == 0 {
+			return "(empty)", nil
+		}
+		parts := make([]string, len(elements))
+		for i, element := range elements {
+			part, err := jsonValue(element)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, ","), nil
+	case 't':
+		return "T", nil
+	case 'f':
+		return "F", nil
+	case 'n':
+		return "-", nil
+	}
+
+	return string(raw), nil
+}
@@ -0,0 +1,114 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var jsonLogpath = "../sample_logs/conn.json"
+
+func TestJSONLog(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(jsonLogpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := parser.Path()
+	assert.Nil(err)
+	assert.Equal(path, "conn", "log type should come from _path")
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto", "local_orig", "tunnel_parents"}, "parsed fields incorrectly")
+
+	parser.SetFields(fields)
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+
+	assert.Equal(len(rows), 2, "parsed wrong number of entries")
+	assert.Equal(rows[0], []string{"1452684903.9084", "CbOiIv2wbbH7F25W21", "10.1.20.227", "37218", "204.238.149.187", "443", "tcp", "T", "(empty)"}, "parsed entries incorrectly")
+	assert.Equal(rows[1][7], "-", "missing keys should be unset")
+	assert.Equal(rows[1][8], "CHhAvVGS1DHFjwGM9,ClEkJM2Vm5giqnMf4h", "arrays should be joined")
+}
+
+func TestJSONMetaKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(jsonLogpath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetKeepMetaKeys("_write_ts")
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields[0], "_write_ts", "kept meta keys should be fields")
+
+	parser.SetFields([]string{"_write_ts", "id.resp_h"})
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+	assert.Equal(rows, [][]string{
+		{"2016-01-13T11:35:04.000000Z", "204.238.149.187"},
+		{"2016-01-13T11:35:05.000000Z", "8.8.8.8"},
+	}, "parsed entries incorrectly")
+}
+
+func TestJSONLogKeysMissingFromFirstObject(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"dns.json": {Data: []byte(`{"_path":"dns","ts":1452684903.9084,"uid":"C1"}
+{"_path":"dns","ts":1452684904.1,"uid":"C2","query":"example.com"}
+{"_path":"dns","ts":1452684905.2,"uid":"C3","rcode":3,"query":"example.org"}
+`)}}
+
+	parser, err := NewParserFS(fsys, "dns.json", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "query", "rcode"}, "expected the keys of every object as fields")
+
+	parser.SetFields(fields)
+	parser.CreateErrorBuffer(10)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"1452684903.9084", "C1", "-", "-"},
+		{"1452684904.1", "C2", "example.com", "-"},
+		{"1452684905.2", "C3", "example.org", "3"},
+	}, "expected the values of keys missing from the first object")
+	assert.Equal(len(parser.Errors), 0, "expected no keys to be dropped")
+
+	// Keys that weren't found when the header was read are reported
+	entry, err := parser.jsonEntry(&header{json: true, fields: []string{"ts", "uid"}}, 4, `{"_path":"dns","ts":1452684906.3,"uid":"C4","qtype":1}`)
+	assert.Nil(err)
+	assert.Equal(entry, []string{"1452684906.3", "C4"}, "expected the values of the fields")
+	if assert.Equal(len(parser.Errors), 1, "expected the unseen key to be reported") {
+		assert.EqualError(<-parser.Errors, "Entry on line 4 has key qtype that is not a field, its value is dropped")
+	}
+}
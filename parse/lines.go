@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ReadLines returns the rows on the given 1-based line numbers of the Bro
+// log, in the order of lineNums, ex: to read again the hits of an index.
+// The log is read once, up to the last line wanted. Rows are the same as
+// the ones BufferRow pushes before any Parse() function, with either all
+// fields or the specific fields to parse. A line without a row, such as a
+// header line, is an error.
+func (p *Parser) ReadLines(lineNums []int) ([][]string, error) {
+
+	wanted := make(map[int][]string, len(lineNums))
+	last := 0
+	for _, lineNum := range lineNums {
+		wanted[lineNum] = nil
+		if lineNum > last {
+			last = lineNum
+		}
+	}
+
+	err := p.eachRow(func(lineNum int, row []string) error {
+		if _, ok := wanted[lineNum]; ok {
+			wanted[lineNum] = row
+		}
+		if lineNum >= last {
+			return errStopEmitting
+		}
+		return nil
+	})
+	if err != nil && err != errStopEmitting {
+		return nil, err
+	}
+
+	rows := make([][]string, len(lineNums))
+	for i, lineNum := range lineNums {
+		if wanted[lineNum] == nil {
+			return nil, errors.New("No row on line " + strconv.Itoa(lineNum))
+		}
+		rows[i] = wanted[lineNum]
+	}
+
+	return rows, nil
+}
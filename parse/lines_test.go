@@ -0,0 +1,27 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLines(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+
+	rows, err := parser.ReadLines([]int{12, 10})
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"ClEkJM2Vm5giqnMf4h", "icmp"}, {"C7fIlMZDuRiqjpYbb", "udp"}}, "read the wrong lines")
+
+	_, err = parser.ReadLines([]int{9, 7})
+	assert.EqualError(err, "No row on line 7", "expected an error for a header line")
+
+	_, err = parser.ReadLines([]int{100})
+	assert.NotNil(err, "expected an error for a line past the end of the log")
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"encoding/hex"
+	"net"
+)
+
+// MACError reports a value of a MAC address field that isn't a MAC address.
+type MACError struct {
+	Field string
+	Value string
+}
+
+func (e *MACError) Error() string {
+	return "Malformed MAC address in " + e.Field + ": " + e.Value
+}
+
+// NormalizeMACParse returns a Parse() function that rewrites the MAC
+// addresses of fields (ex: "mac" of a dhcp.log) in their canonical form,
+// lowercase and colon separated, ex: "00:0c:29:3a:b1:5f", so that MACs can
+// be joined on across logs. MACs may be colon or dash separated, in dotted
+// groups of 4 hex digits, or 12 hex digits without separators. Unset and
+// empty values ("-" and "(empty)") are left as is, and so are malformed
+// MACs, which are reported as a MACError.
+func NormalizeMACParse(fields []string) Parse {
+	return func(rowFields, row []string) ([]string, error) {
+
+		normalized := append([]string{}, row...)
+		warning := &ParseWarning{}
+
+		for _, field := range fields {
+			index, err := getIndex(rowFields, field)
+			if err != nil || index >= len(normalized) {
+				continue
+			}
+
+			value := normalized[index]
+			if value == DefaultFieldMarkers.Unset || value == DefaultFieldMarkers.Empty {
+				continue
+			}
+
+			mac, ok := normalizeMAC(value)
+			if !ok {
+				warning.Errs = append(warning.Errs, &MACError{Field: field, Value: value})
+				continue
+			}
+			normalized[index] = mac
+		}
+
+		if warning.Errs != nil {
+			return normalized, warning
+		}
+		return normalized, nil
+	}
+}
+
+// normalizeMAC returns a MAC address lowercase and colon separated.
+func normalizeMAC(value string) (string, bool) {
+
+	if len(value) == 12 {
+		if b, err := hex.DecodeString(value); err == nil {
+			return net.HardwareAddr(b).String(), true
+		}
+		return "", false
+	}
+
+	mac, err := net.ParseMAC(value)
+	if err != nil || len(mac) != 6 {
+		return "", false
+	}
+	return mac.String(), true
+}
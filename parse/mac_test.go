@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMACParse(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expected := range map[string]string{
+		"00:0C:29:3A:B1:5F": "00:0c:29:3a:b1:5f",
+		"00-0c-29-3a-b1-5f": "00:0c:29:3a:b1:5f",
+		"000c.293a.b15f":    "00:0c:29:3a:b1:5f",
+		"000C293AB15F":      "00:0c:29:3a:b1:5f",
+	} {
+		mac, ok := normalizeMAC(value)
+		assert.True(ok, "expected "+value+" to be a MAC")
+		assert.Equal(mac, expected, "normalized "+value+" incorrectly")
+	}
+
+	fsys := fstest.MapFS{"dhcp.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tmac\thost_name\n" +
+		"#types\tstring\tstring\n" +
+		"00-0C-29-3A-B1-5F\tlaptop\n" +
+		"-\tprinter\n" +
+		"not-a-mac\tphone\n")}}
+
+	parser, err := NewParserFS(fsys, "dhcp.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	parser.CreateErrorBuffer(10)
+	rows, err := parser.ReadAll(NormalizeMACParse([]string{"mac"}))
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"00:0c:29:3a:b1:5f", "laptop"},
+		{"-", "printer"},
+		{"not-a-mac", "phone"},
+	}, "expected MACs normalized, and unset and malformed values left as is")
+
+	macErr := (<-parser.Errors).(*MACError)
+	assert.Equal(macErr.Value, "not-a-mac", "expected the malformed MAC reported")
+}
@@ -0,0 +1,138 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ManifestEntry describes a processed Bro log, for provenance tracking.
+// First and Last are the earliest and latest ts of its entries, and are
+// zero for logs without a ts field. Error is set instead for a log that
+// couldn't be read.
+type ManifestEntry struct {
+	Path   string    `json:"path"`
+	Type   string    `json:"type"`
+	Rows   int       `json:"rows"`
+	SHA256 string    `json:"sha256"`
+	First  time.Time `json:"first"`
+	Last   time.Time `json:"last"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// BuildManifest reads each Bro log once, recording its type from the
+// #path header, its number of entries, the SHA-256 checksum of the file
+// and the time window its entries span. Logs that can't be read don't stop
+// the others: they are recorded in the manifest with their error, and
+// returned together as a *DirError along with the manifest, which can be
+// saved and completed later with ResumeManifest.
+func BuildManifest(paths []string) ([]ManifestEntry, error) {
+	return ResumeManifest(nil, paths)
+}
+
+// ResumeManifest is BuildManifest, but builds on manifest, ex: one saved by
+// an interrupted or failed BuildManifest. The logs it already has an entry
+// for are skipped, and the ones recorded with an error are read again.
+// Entries for the other logs are appended, and manifest isn't modified.
+func ResumeManifest(manifest []ManifestEntry, paths []string) ([]ManifestEntry, error) {
+
+	resumed := make([]ManifestEntry, len(manifest), len(manifest)+len(paths))
+	copy(resumed, manifest)
+
+	indexes := make(map[string]int, len(resumed))
+	for i, entry := range resumed {
+		indexes[entry.Path] = i
+	}
+
+	failed := make(map[string]error)
+	for _, path := range paths {
+		i, ok := indexes[path]
+		if ok && resumed[i].Error == "" {
+			continue
+		}
+
+		entry, err := buildManifestEntry(path)
+		if err != nil {
+			entry = ManifestEntry{Path: path, Error: err.Error()}
+			failed[path] = err
+		}
+
+		if ok {
+			resumed[i] = entry
+			continue
+		}
+		indexes[path] = len(resumed)
+		resumed = append(resumed, entry)
+	}
+
+	if len(failed) > 0 {
+		return resumed, &DirError{Files: failed}
+	}
+	return resumed, nil
+}
+
+func buildManifestEntry(path string) (ManifestEntry, error) {
+
+	entry := ManifestEntry{Path: path}
+
+	// The file is checksummed as the entries are read from it
+	var hasher hash.Hash
+	p, err := NewParserReader(path, func(offset int64) (io.ReadCloser, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		hasher = sha256.New()
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(file, hasher), file}, nil
+	}, true)
+	if err != nil {
+		return entry, err
+	}
+
+	tsIndex := -1
+	p.SetHeaderCallback(func(key, value string) {
+		switch key {
+		case "path":
+			entry.Type = value
+		case "fields":
+			tsIndex = -1
+			for i, field := range strings.Split(value, p.separator) {
+				if field == "ts" {
+					tsIndex = i
+				}
+			}
+		}
+	})
+
+	err = p.eachEntry(func(lineNum int, values []string) error {
+		entry.Rows++
+
+		if tsIndex < 0 || tsIndex >= len(values) {
+			return nil
+		}
+		ts, err := ParseTime(values[tsIndex])
+		if err != nil {
+			return nil
+		}
+		if entry.First.IsZero() || ts.Before(entry.First) {
+			entry.First = ts
+		}
+		if ts.After(entry.Last) {
+			entry.Last = ts
+		}
+		return nil
+	})
+	if err != nil {
+		return entry, err
+	}
+
+	entry.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	return entry, nil
+}
@@ -0,0 +1,69 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	paths := []string{logpath, "../sample_logs/unordered.log"}
+	manifest, err := BuildManifest(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(len(manifest), 2, "expected an entry per log")
+
+	data, err := os.ReadFile(logpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := sha256.Sum256(data)
+
+	assert.Equal(manifest[0].Path, logpath, "path is incorrect")
+	assert.Equal(manifest[0].Type, "conn", "type is incorrect")
+	assert.Equal(manifest[0].Rows, 1, "rows are incorrect")
+	assert.Equal(manifest[0].SHA256, hex.EncodeToString(checksum[:]), "checksum is incorrect")
+	assert.Equal(manifest[0].First, time.Unix(1452684903, 908400000).UTC(), "time window is incorrect")
+	assert.Equal(manifest[0].Last, manifest[0].First, "time window is incorrect")
+
+	assert.Equal(manifest[1].Rows, 4, "rows are incorrect")
+	assert.Equal(manifest[1].Last, time.Unix(1452684906, 0).UTC(), "time window should span the entries out of order")
+
+	// Logs that can't be read are recorded, and completed when resumed
+	dir := t.TempDir()
+	late := filepath.Join(dir, "conn.log")
+	manifest, err = BuildManifest([]string{logpath, late})
+	if assert.IsType(err, &DirError{}) {
+		assert.Contains(err.(*DirError).Files, late, "expected the missing log to fail")
+	}
+	assert.Equal(len(manifest), 2, "expected an entry per log, even a failed one")
+	assert.Equal(manifest[0].Rows, 1, "the other logs should still be read")
+	assert.Equal(manifest[1].Path, late, "path is incorrect")
+	assert.NotEqual(manifest[1].Error, "", "expected the error of the missing log")
+
+	if err := os.WriteFile(late, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := ResumeManifest([]ManifestEntry{{Path: logpath, Rows: 99}}, []string{logpath, "../sample_logs/unordered.log"})
+	assert.Nil(err)
+	assert.Equal(len(resumed), 2, "expected an entry per log")
+	assert.Equal(resumed[0].Rows, 99, "expected logs already in the manifest to be skipped")
+	assert.Equal(resumed[1].Rows, 4, "expected the new log to be appended")
+
+	resumed, err = ResumeManifest(manifest, []string{logpath, late})
+	assert.Nil(err)
+	assert.Equal(len(resumed), 2, "expected the failed log to be read again in place")
+	assert.Equal(resumed[1].Error, "", "expected the error to be cleared")
+	assert.Equal(resumed[1].SHA256, hex.EncodeToString(checksum[:]), "checksum is incorrect")
+	assert.NotEqual(manifest[1].Error, "", "the resumed manifest should not be modified")
+}
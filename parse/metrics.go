@@ -0,0 +1,131 @@
+package parse
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes the names of the metrics.
+const metricsNamespace = "gobro"
+
+var (
+	rowsParsedDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "rows_parsed_total"),
+		"Rows parsed from Bro logs.", nil, nil)
+	malformedRowsDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "malformed_rows_total"),
+		"Malformed rows skipped in Bro logs.", nil, nil)
+	bytesReadDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "bytes_read_total"),
+		"Bytes read from Bro logs.", nil, nil)
+)
+
+// parseMetrics counts the work of parsers for monitoring, as a
+// prometheus.Collector of metrics prefixed with gobro_. It is shared by the
+// parsers registering metrics with the same prometheus.Registerer.
+type parseMetrics struct {
+	rowsParsed    atomic.Uint64
+	malformedRows atomic.Uint64
+	bytesRead     atomic.Uint64
+	parseDuration prometheus.Histogram
+
+	// parent is updated along with the metrics, ex: by the metrics of a
+	// single parse for its summary
+	parent *parseMetrics
+}
+
+// newParseMetrics returns new metrics, with every count at 0.
+func newParseMetrics() *parseMetrics {
+	return &parseMetrics{parseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "parse_duration_seconds",
+		Help:      "Duration of the parses of Bro logs.",
+		Buckets:   prometheus.DefBuckets,
+	})}
+}
+
+// SetMetrics registers the metrics of the parser with registerer, ex: a
+// prometheus.Registry served by promhttp at the /metrics endpoint of a long
+// running parsing service, and makes the parser update them while parsing:
+// gobro_rows_parsed_total for the rows emitted (ex: by BufferRow),
+// gobro_malformed_rows_total for the malformed rows skipped,
+// gobro_bytes_read_total for the bytes read from the Bro log and the
+// gobro_parse_duration_seconds histogram of how long every parse took.
+// Parsers registering with the same registerer share their metrics. An
+// error is returned if other metrics are registered with the same names.
+func (p *Parser) SetMetrics(registerer prometheus.Registerer) error {
+
+	m := newParseMetrics()
+	if err := registerer.Register(m); err != nil {
+		registered, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return err
+		}
+		if m, ok = registered.ExistingCollector.(*parseMetrics); !ok {
+			return err
+		}
+	}
+
+	p.metrics = m
+	return nil
+}
+
+// Describe sends the descriptors of the metrics to ch, for
+// prometheus.Collector.
+func (m *parseMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rowsParsedDesc
+	ch <- malformedRowsDesc
+	ch <- bytesReadDesc
+	m.parseDuration.Describe(ch)
+}
+
+// Collect sends the current values of the metrics to ch, for
+// prometheus.Collector.
+func (m *parseMetrics) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(rowsParsedDesc, prometheus.CounterValue, float64(m.rowsParsed.Load()))
+	ch <- prometheus.MustNewConstMetric(malformedRowsDesc, prometheus.CounterValue, float64(m.malformedRows.Load()))
+	ch <- prometheus.MustNewConstMetric(bytesReadDesc, prometheus.CounterValue, float64(m.bytesRead.Load()))
+	m.parseDuration.Collect(ch)
+}
+
+// The update methods do nothing on nil metrics, for parsers without any.
+
+func (m *parseMetrics) addRow() {
+	for ; m != nil; m = m.parent {
+		m.rowsParsed.Add(1)
+	}
+}
+
+func (m *parseMetrics) addMalformedRow() {
+	for ; m != nil; m = m.parent {
+		m.malformedRows.Add(1)
+	}
+}
+
+func (m *parseMetrics) addBytesRead(n int) {
+	for ; m != nil; m = m.parent {
+		m.bytesRead.Add(uint64(n))
+	}
+}
+
+func (m *parseMetrics) observeDuration(start time.Time) {
+	duration := time.Since(start).Seconds()
+	for ; m != nil; m = m.parent {
+		// The metrics of a single parse don't keep a histogram
+		if m.parseDuration != nil {
+			m.parseDuration.Observe(duration)
+		}
+	}
+}
+
+// countingReader counts the bytes read from r into metrics.
+type countingReader struct {
+	r       io.Reader
+	metrics *parseMetrics
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.metrics.addBytesRead(n)
+	return n, err
+}
@@ -0,0 +1,86 @@
+package parse
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	log := followHeader +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"1452684904.908400\tC7fIlMZDuRiqjpYbb\n" +
+		"1452684905.908400\tCxPe2flVpE5gerpQc4\tudp\n"
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(log)}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+
+	registry := prometheus.NewRegistry()
+	assert.Nil(parser.SetMetrics(registry))
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+	for range parser.Row {
+	}
+
+	recorder := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	assert.Contains(body, "# TYPE gobro_rows_parsed_total counter\ngobro_rows_parsed_total 2\n", "rows parsed are incorrect")
+	assert.Contains(body, "\ngobro_malformed_rows_total 1\n", "malformed rows are incorrect")
+	assert.Contains(body, "\ngobro_bytes_read_total "+strconv.Itoa(len(log))+"\n", "bytes read are incorrect")
+	assert.Contains(body, "# TYPE gobro_parse_duration_seconds histogram\n", "expected the parse duration")
+	assert.Contains(body, "\ngobro_parse_duration_seconds_count 1\n", "expected the parse to be observed")
+	assert.True(strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"), "expected the text exposition format")
+
+	// Parsers registering with the same registry share their metrics
+	other, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.SetFields([]string{"ts", "uid", "proto"})
+	assert.Nil(other.SetMetrics(registry))
+	if _, err := other.ReadAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.GetCounter() != nil:
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		case metric.GetHistogram() != nil:
+			values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+	assert.Equal(values, map[string]float64{
+		"gobro_rows_parsed_total":      4,
+		"gobro_malformed_rows_total":   2,
+		"gobro_bytes_read_total":       float64(2 * len(log)),
+		"gobro_parse_duration_seconds": 2,
+	}, "expected the metrics of both parsers to be collected")
+
+	// Other metrics with the same names can't be registered
+	conflicting := prometheus.NewRegistry()
+	conflicting.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "gobro_rows_parsed_total", Help: "Rows."}))
+	assert.NotNil(parser.SetMetrics(conflicting), "expected an error for metrics with the same names")
+}
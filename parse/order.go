@@ -0,0 +1,48 @@
+package parse
+
+import (
+	"strconv"
+	"time"
+)
+
+// OrderError reports an entry whose ts is earlier than the ts of the
+// entry before it, which usually points to clock skew or logs that were
+// merged in the wrong order.
+type OrderError struct {
+	Line     int
+	Previous time.Time
+	Current  time.Time
+}
+
+func (e *OrderError) Error() string {
+	return "Entry on line " + strconv.Itoa(e.Line) + " is out of order, ts " +
+		e.Current.Format(time.RFC3339Nano) + " is earlier than the previous ts " +
+		e.Previous.Format(time.RFC3339Nano)
+}
+
+// checkEntryOrder compares the ts of an entry against the ts of the
+// previous entry, and returns the ts to compare the next entry against.
+// Entries without a valid ts are not checked.
+func (p *Parser) checkEntryOrder(h *header, lineNum int, entry []string, lastTs time.Time) time.Time {
+
+	fields := h.fields
+	if fields == nil {
+		fields = p.fields
+	}
+
+	index, err := getIndex(fields, "ts")
+	if err != nil || index >= len(entry) {
+		return lastTs
+	}
+
+	ts, err := ParseTime(entry[index])
+	if err != nil {
+		return lastTs
+	}
+
+	if !lastTs.IsZero() && ts.Before(lastTs) {
+		p.reportError(&OrderError{Line: lineNum, Previous: lastTs, Current: ts})
+	}
+
+	return ts
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.SetCheckOrder(true)
+	parser.CreateBuffer(10)
+	parser.CreateErrorBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows int
+	for range parser.Row {
+		rows++
+	}
+	assert.Equal(rows, 4, "entries out of order should still be parsed")
+
+	var errs []error
+	for err := range parser.Errors {
+		errs = append(errs, err)
+	}
+
+	if assert.Equal(len(errs), 1, "expected a single out of order entry") {
+		orderErr, ok := errs[0].(*OrderError)
+		assert.True(ok, "expected an *OrderError")
+		assert.Equal(orderErr.Line, 11, "reported the wrong line")
+		assert.Equal(orderErr.Previous, time.Unix(1452684905, 100000000).UTC(), "reported the wrong previous ts")
+		assert.Equal(orderErr.Current, time.Unix(1452684904, 500000000).UTC(), "reported the wrong ts")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	assert := assert.New(t)
+
+	ts, err := ParseTime("1452684903.908400")
+	assert.Nil(err)
+	assert.Equal(ts, time.Unix(1452684903, 908400000).UTC(), "parsed time incorrectly")
+
+	ts, err = ParseTime("1452684903")
+	assert.Nil(err)
+	assert.Equal(ts, time.Unix(1452684903, 0).UTC(), "parsed time incorrectly")
+
+	ts, err = ParseTime("-1.5")
+	assert.Nil(err)
+	assert.Equal(ts, time.Unix(0, -1500000000).UTC(), "the fraction of a time before the epoch should be negative")
+
+	ts, err = ParseTime("-0.25")
+	assert.Nil(err)
+	assert.Equal(ts, time.Unix(0, -250000000).UTC(), "parsed time just before the epoch incorrectly")
+
+	_, err = ParseTime("1.-5")
+	assert.NotNil(err, "expected an error for a signed fraction")
+
+	_, err = ParseTime("-")
+	assert.NotNil(err, "expected an error for an unset time")
+}
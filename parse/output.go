@@ -0,0 +1,56 @@
+package parse
+
+// SetUnsetOutput sets what the unset value of Bro logs, "-" unless the log
+// declares another #unset_field, is replaced with in the rows pushed by BufferRow or returned by ReadAll, ex: "" or "\N"
+// for Postgres COPY, so that rows can be written as is to sinks with
+// another representation of null values. The row ID and Parse() functions
+// still see the unset value.
+func (p *Parser) SetUnsetOutput(s string) {
+	p.unsetOutput = s
+	p.replaceUnset = true
+}
+
+// unsetValue returns the unset value of the rows emitted.
+func (p *Parser) unsetValue() string {
+	if p.replaceUnset {
+		return p.unsetOutput
+	}
+	return p.markers.Unset
+}
+
+// output returns a row as it should be emitted, after Parse() functions
+// turned rawRow into row.
+func (p *Parser) output(rawRow, row []string) []string {
+
+	if p.computedFields != nil {
+		computed, err := p.computeFields(rawRow)
+		if err != nil {
+			p.reportError(err)
+			computed = make([]string, len(p.computedFields))
+			for i := range computed {
+				computed[i] = p.markers.Unset
+			}
+		}
+		row = append(row[:len(row):len(row)], computed...)
+	}
+
+	if p.sourceFields {
+		row = append(row[:len(row):len(row)], p.sourceValues()...)
+	}
+
+	if p.replaceUnset {
+		replaced := make([]string, len(row))
+		for i, value := range row {
+			if value == p.markers.Unset {
+				value = p.unsetOutput
+			}
+			replaced[i] = value
+		}
+		row = replaced
+	}
+
+	if p.rowID {
+		return withRowID(rawRow, row)
+	}
+	return row
+}
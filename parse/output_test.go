@@ -0,0 +1,35 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUnsetOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(followHeader +
+		"1452684903.908400\t-\ttcp\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0][1], "-", "unset values should be kept by default")
+
+	parser.SetUnsetOutput("\\N")
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0], []string{"1452684903.908400", "\\N", "tcp"}, "unset values should be replaced")
+
+	parser.SetUnsetOutput("")
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows[0][1], "", "unset values should be replaced with empty strings")
+}
@@ -0,0 +1,269 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetColumn describes how a Bro field is stored in Parquet.
+type parquetColumn struct {
+	name        string
+	elementType string
+	repeated    bool
+}
+
+// parquetColumnFor maps a Bro type to a Parquet column. Sets and vectors
+// become repeated columns of their element type.
+func parquetColumnFor(name, broType string) parquetColumn {
+
+	column := parquetColumn{name: name, elementType: broType}
+	if isContainerType(broType) {
+		column.repeated = true
+		column.elementType = containerElementType(broType)
+	}
+
+	return column
+}
+
+// structField returns the field of the column in the struct the schema is
+// built from. Counts and ports are unsigned integers, doubles and intervals
+// floats, times UTC timestamps in microseconds and any other type a UTF-8
+// string. Columns are optional, or repeated for sets and vectors.
+func (c parquetColumn) structField(index int) reflect.StructField {
+
+	var goType reflect.Type
+	tag := c.name
+	switch c.elementType {
+	case "bool":
+		goType = reflect.TypeOf(false)
+	case "count":
+		goType = reflect.TypeOf(uint64(0))
+	case "int":
+		goType = reflect.TypeOf(int64(0))
+	case "port":
+		goType = reflect.TypeOf(uint16(0))
+	case "double", "interval":
+		goType = reflect.TypeOf(float64(0))
+	case "time":
+		goType = reflect.TypeOf(int64(0))
+		tag += ",timestamp(microsecond)"
+	default:
+		goType = reflect.TypeOf("")
+	}
+
+	if c.repeated {
+		goType = reflect.SliceOf(goType)
+	} else {
+		goType = reflect.PointerTo(goType)
+	}
+
+	return reflect.StructField{
+		Name: "Field" + strconv.Itoa(index),
+		Type: goType,
+		Tag:  reflect.StructTag(`parquet:"` + tag + `"`),
+	}
+}
+
+// ParquetSink writes rows to w as a Parquet file, for loading Bro logs into
+// analytics tools such as DuckDB or Spark. Column types are mapped from the
+// #types header, unset values are written as nulls and sets and vectors as
+// repeated columns. Rows are buffered and every batch is written as a row
+// group. Close must be called to write the file footer.
+type ParquetSink struct {
+	writer    *parquet.Writer
+	columns   []parquetColumn
+	batchSize int
+	batch     []parquet.Row
+	numRows   int64
+	markers   FieldMarkers
+}
+
+// NewParquetSink returns a sink writing fields of the given Bro types to w,
+// ex: the fields and types returned by Schema. Batches hold 10000 rows by default.
+func NewParquetSink(w io.Writer, fields, types []string) (*ParquetSink, error) {
+
+	if len(fields) != len(types) {
+		return nil, errors.New("Fields and types must be the same length")
+	}
+
+	// The schema is built from a struct rather than a parquet.Group, which
+	// orders its columns by name, to keep the columns in the order of the log
+	s := &ParquetSink{batchSize: 10000, markers: DefaultFieldMarkers}
+	structFields := make([]reflect.StructField, len(fields))
+	for i, field := range fields {
+		s.columns = append(s.columns, parquetColumnFor(field, types[i]))
+		structFields[i] = s.columns[i].structField(i)
+	}
+
+	schema := parquet.SchemaOf(reflect.New(reflect.StructOf(structFields)).Interface())
+	s.writer = parquet.NewWriter(w, schema)
+
+	return s, nil
+}
+
+// SetBatchSize sets the number of rows written in each row group.
+func (s *ParquetSink) SetBatchSize(size int) {
+	s.batchSize = size
+}
+
+// SetFieldMarkers sets the unset, empty and set separator markers of the
+// rows written, ex: the ones returned by the FieldMarkers of their parser.
+func (s *ParquetSink) SetFieldMarkers(markers FieldMarkers) {
+	s.markers = markers.orDefault()
+}
+
+// Write converts and buffers a row, and writes a row group once the batch
+// is full. A row with a value that doesn't match its type is rejected, and
+// the rows buffered before it are kept.
+func (s *ParquetSink) Write(row []string) error {
+
+	if len(row) != len(s.columns) {
+		return errors.New("Row has " + strconv.Itoa(len(row)) + " values but there are " + strconv.Itoa(len(s.columns)) + " columns")
+	}
+
+	var values parquet.Row
+	for index, column := range s.columns {
+		var err error
+		values, err = column.values(values, index, row[index], s.markers)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.batch = append(s.batch, values)
+	if len(s.batch) >= s.batchSize {
+		return s.flush()
+	}
+
+	return nil
+}
+
+// Close writes any buffered rows and the file footer. The underlying
+// writer is not closed.
+func (s *ParquetSink) Close() error {
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	return s.writer.Close()
+}
+
+// flush writes the buffered rows as a row group.
+func (s *ParquetSink) flush() error {
+
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	rows := s.batch
+	s.batch = nil
+
+	if _, err := s.writer.WriteRows(rows); err != nil {
+		return err
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+
+	s.numRows += int64(len(rows))
+
+	return nil
+}
+
+// values appends the values of a Bro value of column index to row, with
+// their repetition and definition levels. Set elements are values of their
+// own, and unset values and empty sets a single null.
+func (c parquetColumn) values(row parquet.Row, index int, value string, markers FieldMarkers) (parquet.Row, error) {
+
+	var elements []string
+	switch {
+	case value == markers.Unset:
+	case c.repeated && value == markers.Empty:
+	case c.repeated:
+		elements = strings.Split(value, markers.SetSeparator)
+	case value == markers.Empty && c.isString():
+		elements = []string{""}
+	default:
+		elements = []string{value}
+	}
+
+	if len(elements) == 0 {
+		return append(row, parquet.NullValue().Level(0, 0, index)), nil
+	}
+
+	for i, element := range elements {
+		v, err := c.value(element)
+		if err != nil {
+			return nil, err
+		}
+
+		repetitionLevel := 0
+		if i > 0 {
+			repetitionLevel = 1
+		}
+		row = append(row, v.Level(repetitionLevel, 1, index))
+	}
+
+	return row, nil
+}
+
+// value converts a single non null value of the element type of the column.
+func (c parquetColumn) value(value string) (parquet.Value, error) {
+
+	invalid := errors.New("Value " + value + " of " + c.name + " is not a valid " + c.elementType)
+
+	switch c.elementType {
+	case "bool":
+		if value != "T" && value != "F" {
+			return parquet.Value{}, invalid
+		}
+		return parquet.BooleanValue(value == "T"), nil
+	case "count":
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return parquet.Value{}, invalid
+		}
+		return parquet.Int64Value(int64(count)), nil
+	case "int":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return parquet.Value{}, invalid
+		}
+		return parquet.Int64Value(i), nil
+	case "port":
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return parquet.Value{}, invalid
+		}
+		return parquet.Int32Value(int32(port)), nil
+	case "double", "interval":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return parquet.Value{}, invalid
+		}
+		return parquet.DoubleValue(f), nil
+	case "time":
+		ts, err := ParseTime(value)
+		if err != nil {
+			return parquet.Value{}, invalid
+		}
+		return parquet.Int64Value(ts.UnixMicro()), nil
+	}
+
+	return parquet.ByteArrayValue([]byte(value)), nil
+}
+
+// isString reports whether values of the column are stored as strings.
+func (c parquetColumn) isString() bool {
+	switch c.elementType {
+	case "bool", "count", "int", "port", "double", "interval", "time":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// readParquet reads back the rows of a Parquet file, along with its schema
+// and the number of rows of each row group.
+func readParquet(t *testing.T, data []byte) (*parquet.Schema, []int64, []parquet.Row) {
+
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []int64
+	var rows []parquet.Row
+	for _, group := range file.RowGroups() {
+		groups = append(groups, group.NumRows())
+
+		reader := group.Rows()
+		buf := make([]parquet.Row, group.NumRows())
+		n, err := reader.ReadRows(buf)
+		if err != nil && n != len(buf) {
+			t.Fatal(err)
+		}
+		reader.Close()
+		rows = append(rows, buf[:n]...)
+	}
+
+	return file.Schema(), groups, rows
+}
+
+func TestParquetSink(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	sink, err := NewParquetSink(&out,
+		[]string{"ts", "uid", "id.orig_p", "orig_bytes", "duration", "tunnel_parents"},
+		[]string{"time", "string", "port", "count", "interval", "set[string]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.SetBatchSize(2)
+
+	rows := make(chan []string, 3)
+	rows <- []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "443", "100", "0.5", "C1,C2"}
+	rows <- []string{"1452684904.908400", "(empty)", "-", "-", "-", "(empty)"}
+	rows <- []string{"1452684905.908400", "C7fIlMZDuRiqjpYbb", "53", "0", "1.5", "-"}
+	close(rows)
+
+	err = Drain(rows, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(sink.numRows, int64(3), "all rows should be written")
+
+	schema, groups, read := readParquet(t, out.Bytes())
+	assert.Equal(groups, []int64{2, 1}, "each batch should be a row group")
+
+	// Columns keep the order of the fields, with their types mapped
+	columns := schema.Fields()
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.Name())
+	}
+	assert.Equal(names, []string{"ts", "uid", "id.orig_p", "orig_bytes", "duration", "tunnel_parents"}, "expected the columns in order")
+	var types []string
+	for _, column := range columns {
+		types = append(types, column.Type().String())
+	}
+	assert.Equal(types, []string{"TIMESTAMP(isAdjustedToUTC=true,unit=MICROS)", "STRING", "INT(16,false)", "INT(64,false)", "DOUBLE", "STRING"}, "expected the types mapped from the Bro types")
+	assert.True(columns[5].Repeated(), "expected sets to be repeated")
+
+	assert.Equal(len(read), 3, "expected every row to be read back")
+	assert.Equal(read[0][0].Int64(), int64(1452684903908400), "expected the time in microseconds")
+	assert.Equal(read[0][1].String(), "CbOiIv2wbbH7F25W21", "expected the string")
+	assert.Equal(read[0][2].Int32(), int32(443), "expected the port")
+	assert.Equal(read[0][3].Int64(), int64(100), "expected the count")
+	assert.Equal(read[2][4].Double(), 1.5, "expected the interval")
+
+	var parents []string
+	for _, value := range read[0][5:] {
+		parents = append(parents, value.String())
+	}
+	assert.Equal(parents, []string{"C1", "C2"}, "expected the elements of the set")
+
+	assert.Equal(read[1][1].String(), "", "expected empty strings for empty values")
+	assert.True(read[1][2].IsNull(), "expected the unset port to be a null")
+	assert.Equal(len(read[1]), 6, "expected a single null for the empty set")
+	assert.True(read[1][5].IsNull(), "expected the empty set to be a null")
+	assert.True(read[2][5].IsNull(), "expected the unset set to be a null")
+}
+
+func TestParquetSinkInvalidValue(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	sink, err := NewParquetSink(&out, []string{"uid", "orig_bytes"}, []string{"string", "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.SetBatchSize(2)
+
+	assert.Nil(sink.Write([]string{"C1", "100"}))
+	assert.EqualError(sink.Write([]string{"C2", "lots"}), "Value lots of orig_bytes is not a valid count", "expected an error for a value that doesn't match its type")
+	assert.Nil(sink.Write([]string{"C3", "200"}), "the rows after an invalid row should still be written")
+	assert.Nil(sink.Write([]string{"C4", "-"}))
+	assert.Nil(sink.Close())
+	assert.Equal(sink.numRows, int64(3), "expected the invalid row to be left out")
+
+	_, groups, read := readParquet(t, out.Bytes())
+	assert.Equal(groups, []int64{2, 1}, "expected the valid rows in each row group")
+	var uids []string
+	for _, row := range read {
+		uids = append(uids, row[0].String())
+	}
+	assert.Equal(uids, []string{"C1", "C3", "C4"}, "expected the valid rows")
+}
@@ -1,6 +1,16 @@
 /*
 Package parse is a Go library for parsing Bro logs and working with
 Bro log data.
+
+The parse package doesn't depend on a config file. The specific fields to
+parse can come from anywhere, ex: config.toml through the config package,
+a command line flag or an environment variable, and are set with SetFields:
+
+	parser, err := parse.NewParser(path, false)
+	parser.SetFields(parse.FieldsFromList(os.Getenv("GOBRO_FIELDS")))
+
+SetAllFields switches between parsing those fields and all of the fields
+of the Bro log.
 */
 package parse
 
@@ -10,8 +20,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/encoding"
 )
 
 // Parser manages the structure of a Bro log.
@@ -19,20 +35,86 @@ import (
 // fields and row slices, share a 1 to 1 mapping.
 // Ex: fields[0] is the value at row[0]
 // FieldsIndex, is only used when a specific set of fields are
-// selected to be parsed. These are defined in config/config.toml
-// The allFields field determins whether you want to use specifc fields from the config
+// selected to be parsed, ex: from config/config.toml or with SetFields.
+// The allFields field determins whether you want to use specifc fields
 // or all of the fields in the Bro log.
 // Augmented values are produced by defining specific Parse() functions.
 type Parser struct {
-	allFields   bool
-	fields      []string
-	fieldsIndex []int
-	filepath    string
-	Row         chan []string
+	allFields          bool
+	trimValues         bool
+	sortContainers     bool
+	collapseSeparators bool
+	reassembleJSON     bool
+	controlBytes       string
+	fields             []string
+	fieldsIndex        []int
+	filepath           string
+	fsys               fs.FS
+	opener             func(int64) (io.ReadCloser, error)
+	separator          string
+	markers            FieldMarkers
+	framing            string
+	checkOrder         bool
+	keepMetaKeys       map[string]bool
+	jsonFields         []string
+	fieldNameTransform func(string) string
+	follow             time.Duration
+	stop               chan struct{}
+	stopOnce           *sync.Once
+	pauseMu            sync.Mutex
+	resumed            chan struct{}
+	maxKeys            int
+	readRetries        int
+	metrics            *parseMetrics
+	tee                io.Writer
+	readBackoff        time.Duration
+	deadline           time.Duration
+	textEncoding       encoding.Encoding
+	decompressWorkers  int
+	filter             func([]string) bool
+	blocklists         map[string]map[string]bool
+	offset             int
+	limit              int
+	rowID              bool
+	fieldOrder         []string
+	computedFields     []computedField
+	fieldTransforms    map[string]func(string) (string, error)
+	errorTolerances    map[string]float64
+	transformIndex     []fieldTransform
+	outputSeparator    string
+	format             Format
+	omitUnset          bool
+	unsetOutput        string
+	replaceUnset       bool
+	sidecarHeader      *header
+	profiling          bool
+	profile            *ParseProfile
+	tolerateTail       bool
+	fieldPresence      bool
+	recordSplitter     bufio.SplitFunc
+	summarizing        bool
+	summaryFields      []string
+	summary            *ParseSummary
+	headerBlocks       int
+	rangeStart         int64
+	rangeEnd           int64
+	blockFields        []string
+	blockPath          string
+	sourceFields       bool
+	headerCallback     func(string, string)
+	types              []string
+	valueIndex         map[string]int
+	coercers           map[string]func(string) (interface{}, error)
+	humanizedNumbers   bool
+	Row                chan []string
+	Errors             chan error
+	results            chan Result
+	TypedRowMap        chan TypedMap
 }
 
-// NewParser validates the Bro log exists and returns a new parser
-// to perform parsing actions on.
+// NewParser validates the Bro log exists, and returns a new parser to
+// perform parsing actions on. The log isn't read, so that pipes and FIFOs
+// are left for parsing, see Probe to check that it looks like a Bro log.
 func NewParser(path string, allFields bool) (*Parser, error) {
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -40,14 +122,183 @@ func NewParser(path string, allFields bool) (*Parser, error) {
 	}
 
 	p := new(Parser)
+	p.markers = DefaultFieldMarkers
+	p.filepath = path
+	p.allFields = allFields
+	return p, nil
+}
+
+// NewParserFS validates the Bro log exists in fsys, and returns a new
+// parser that reads the log through fsys instead of the OS filesystem.
+func NewParserFS(fsys fs.FS, path string, allFields bool) (*Parser, error) {
+
+	if _, err := fs.Stat(fsys, path); err != nil {
+		return nil, errors.New("File path does not exist")
+	}
+
+	p := new(Parser)
+	p.markers = DefaultFieldMarkers
 	p.filepath = path
+	p.fsys = fsys
 	p.allFields = allFields
 	return p, nil
 }
 
-// SetFields assigns the fields to be parsed.
+// NewParserAuto returns a new parser whose fields are picked by the type
+// of the Bro log, read from its #path header. fieldSets maps log types to
+// the specific fields to parse, ex: the [parser] section of config.toml.
+// Log types without fields in fieldSets are parsed with all of their fields.
+func NewParserAuto(path string, fieldSets map[string][]string) (*Parser, error) {
+
+	p, err := NewParser(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	logType, err := p.Path()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := fieldSets[logType]
+	if len(fields) == 0 {
+		p.allFields = true
+		fields, err = p.ParseAllFields()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.SetFields(fields)
+	return p, nil
+}
+
+// open opens the Bro log for reading, either from the OS filesystem, the
+// fs.FS or the stream opener of the parser, decompressing it if needed.
+func (p *Parser) open() (io.ReadCloser, error) {
+	return p.openAt(0)
+}
+
+// openAt is open, with reading starting at a byte offset of the Bro log.
+// Compressed logs can only be read from the start.
+func (p *Parser) openAt(offset int64) (io.ReadCloser, error) {
+
+	if offset > 0 && isCompressed(p.filepath) {
+		return nil, errors.New("Compressed logs can't be read from an offset")
+	}
+
+	// Stream openers start at the offset themselves, ex: with a range request
+	if p.opener != nil {
+		file, err := p.opener(offset)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(p.filepath, p.withRetries(file), p.decompressWorkers)
+	}
+
+	var file io.ReadCloser
+	var err error
+	if p.fsys != nil {
+		file, err = p.fsys.Open(p.filepath)
+	} else {
+		file, err = os.Open(p.filepath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > 0 {
+		if seeker, ok := file.(io.Seeker); ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, file, offset)
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return decompress(p.filepath, p.withRetries(file), p.decompressWorkers)
+}
+
+// SetFields assigns the fields to be parsed. When parsing all fields,
+// the Bro log does not need a #fields header: the fields set here are
+// used as the schema of headerless logs (ex: raw TSV).
 func (p *Parser) SetFields(fields []string) {
 	p.fields = fields
+	p.types = nil
+	p.valueIndex = nil
+	p.transformIndex = nil
+}
+
+// SetAllFields sets whether all of the fields of the Bro log are parsed,
+// or only the specific fields set with SetFields. It overrides the choice
+// made when the parser was created.
+func (p *Parser) SetAllFields(allFields bool) {
+	p.allFields = allFields
+}
+
+// FieldsFromList splits a comma separated list of fields, such as the
+// value of a command line flag or environment variable (ex:
+// "ts, uid,id.orig_h"), into the fields to pass to SetFields.
+func FieldsFromList(list string) []string {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SetTrimValues enables or disables trimming of surrounding whitespace
+// from each value of an entry, before it is emitted or passed to Parse().
+func (p *Parser) SetTrimValues(trim bool) {
+	p.trimValues = trim
+}
+
+// SetCollapseSeparators enables or disables treating a run of separators as
+// a single one, like awk does by default, for exports separated by a
+// variable number of spaces. Leading and trailing separators of a line are
+// ignored too. This only applies to separators made of spaces, so empty
+// values of tab separated logs are kept.
+func (p *Parser) SetCollapseSeparators(collapse bool) {
+	p.collapseSeparators = collapse
+}
+
+// splitValues splits a line on separator, collapsing runs of a separator
+// made of spaces when collapse is enabled.
+func splitValues(line, separator string, collapse bool) []string {
+
+	if !collapse || strings.Trim(separator, " ") != "" {
+		return strings.Split(line, separator)
+	}
+
+	var values []string
+	for _, value := range strings.Split(line, separator) {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	if values == nil {
+		return []string{""}
+	}
+	return values
+}
+
+// SetCheckOrder enables or disables checking that the ts of every entry
+// is not earlier than the ts of the entry before it. Entries out of order
+// are reported as an *OrderError, but are still parsed.
+func (p *Parser) SetCheckOrder(check bool) {
+	p.checkOrder = check
+}
+
+// SetFieldNameTransform sets a function that normalizes field names before
+// the specific fields to parse are matched against the fields of the Bro log,
+// ex: strings.ToLower. It is applied to both sides of the comparison.
+func (p *Parser) SetFieldNameTransform(transform func(string) string) {
+	p.fieldNameTransform = transform
 }
 
 // Fields returns the fields of a bro log.
@@ -84,16 +335,43 @@ func (p *Parser) GetIndexOfFields() error {
 		return errors.New("No specific fields defined for parsing")
 	}
 
+	fieldsIndex, err := p.indexOfFields(allFields)
+	if err != nil {
+		p.fieldsIndex = nil
+		return err
+	}
+
+	p.fieldsIndex = fieldsIndex
+	return nil
+}
+
+// indexOfFields returns the index in allFields of each of the specific
+// fields to be parsed.
+func (p *Parser) indexOfFields(allFields []string) ([]int, error) {
+
+	if p.fieldNameTransform != nil {
+		transformed := make([]string, len(allFields))
+		for i, field := range allFields {
+			transformed[i] = p.fieldNameTransform(field)
+		}
+		allFields = transformed
+	}
+
+	var fieldsIndex []int
+
 	// loop through specific fields
 	for _, configField := range p.fields {
+		if p.fieldNameTransform != nil {
+			configField = p.fieldNameTransform(configField)
+		}
 		index, err := getIndex(allFields, configField)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		p.fieldsIndex = append(p.fieldsIndex, index)
+		fieldsIndex = append(fieldsIndex, index)
 	}
 
-	return nil
+	return fieldsIndex, nil
 }
 
 // GetIndex returns the index of a specific element in a slice.
@@ -104,54 +382,71 @@ func getIndex(allFields []string, configField string) (int, error) {
 		}
 	}
 
-	return -1, errors.New("Couldn't match field defined in config with one in bro log, field is: " + configField)
+	return -1, errors.New("Couldn't match field to parse with one in bro log, field is: " + configField)
 }
 
-// TODO remove hardcoding of the seperator, it could be something
-// other than tabs (research this)?
-
 // ParseAllFields parses the fields of a bro log, and stores them in a
 // slice. Their positions in the bro log correspond to their index's
-// in the slice.
+// in the slice. Fields are split on the separator declared by the
+// #separator header, which defaults to a tab and may be more than one
+// character (ex: "||"). An error is returned if the log has no #fields
+// header.
 func (p *Parser) ParseAllFields() ([]string, error) {
-	var fields []string
 
-	file, fileErr := os.Open(p.filepath)
-	if fileErr != nil {
-		return nil, fileErr
+	h, err := p.readHeader()
+	if err != nil {
+		return nil, err
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
+	if h.fields == nil {
+		return nil, errors.New("No fields header found")
+	}
 
-		if line[0:7] == "#fields" {
+	return h.fields, nil
+}
 
-			if line[8:] == "" {
-				return nil, errors.New("Fields row is malformed")
-			}
+// logFields returns all the fields of the Bro log. Headerless logs, such
+// as raw TSV, have no #fields line to read; when parsing all fields the
+// fields given to SetFields are used as their schema instead.
+func (p *Parser) logFields() ([]string, error) {
 
-			fields = strings.Split(line[8:], "\t")
-			break
-		}
+	h, err := p.readHeader()
+	if err != nil {
+		return nil, err
+	}
 
+	if h.fields == nil {
+		if p.allFields && p.fields != nil {
+			return p.fields, nil
+		}
+		return nil, errors.New("No fields header found, fields must be set via SetFields() to parse a headerless log")
 	}
 
-	return fields, nil
+	return h.fields, nil
 }
 
 // CountLines counts the number of lines in a file.
 // Taken from
 // http://stackoverflow.com/questions/24562942/golang-how-do-i-determine-the-number-of-lines-in-a-file-efficiently.
+// Logs read with SetFraming or SetRecordSplitter have their frames or
+// records counted instead, since a line may hold newlines.
 func (p *Parser) CountLines() (int, error) {
 
-	file, fileErr := os.Open(p.filepath)
+	file, fileErr := p.open()
 	if fileErr != nil {
 		return -1, fileErr
 	}
 	defer file.Close()
 
+	if p.customFraming() {
+		count := 0
+		scanner, _ := p.newScanner(file)
+		for scanner.Scan() {
+			count++
+		}
+		return count, scanner.Err()
+	}
+
 	buf := make([]byte, 32*1024)
 	count := 0
 	lineSep := []byte{'\n'}
@@ -170,8 +465,32 @@ func (p *Parser) CountLines() (int, error) {
 	}
 }
 
+// Count counts the rows matching pred, without pushing them into p.Row.
+// pred is called with the same rows BufferRow would push, containing either
+// all fields or the specific fields to parse: the blocklists, filter,
+// offset and limit apply, but no Parse() functions. A nil pred counts every
+// row. Unlike CountLines, header lines and malformed entries are not counted.
+func (p *Parser) Count(pred func([]string) bool) (int, error) {
+
+	count := 0
+
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		if pred == nil || pred(p.output(row, row)) {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
 // AutoCreateBuffer is a wrapper to initialize the buffer with a size equivalent
-// to the number of lines in a log file.
+// to the number of lines in a log file. The lines are counted exactly, so
+// that BufferRow never blocks on a full buffer before the rows are read. For
+// buffers read from while BufferRow runs, EstimateRows avoids the full pass.
 func (p *Parser) AutoCreateBuffer() error {
 
 	lineNum, err := p.CountLines()
@@ -182,16 +501,370 @@ func (p *Parser) AutoCreateBuffer() error {
 	return nil
 }
 
+// SetTee sets a writer the raw lines of the Bro log are copied to as they
+// are read for parsing, ex: to archive a log while processing it without
+// reading it twice. Compressed logs are copied decompressed. Lines are
+// copied on every pass over the entries (ex: each BufferRow), including
+// the ones read ahead of a pass that stops early, but not when only the
+// header is read. An error writing to w stops the pass.
+func (p *Parser) SetTee(w io.Writer) {
+	p.tee = w
+}
+
 // CreateBuffer initializes the buffer. Without initialization, the channel
 // will block on reads.
 func (p *Parser) CreateBuffer(bufferSize int) {
 	p.Row = make(chan []string, bufferSize)
 }
 
+// CreateErrorBuffer initializes the channel that problems found while
+// parsing are reported on. BufferRow closes it once parsing is done.
+// Without initialization, errors are printed instead. When initialized,
+// the channel must be read from while parsing, or BufferRow will block
+// once it is full.
+func (p *Parser) CreateErrorBuffer(bufferSize int) {
+	p.Errors = make(chan error, bufferSize)
+}
+
+// reportError pushes an error into p.Errors, or prints it when no
+// error buffer was created. Errors found by Results are sent with the rows.
+func (p *Parser) reportError(err error) {
+	if p.results != nil {
+		p.results <- Result{Err: err, Line: errorLine(err)}
+		return
+	}
+	if p.Errors == nil {
+		fmt.Println(err)
+		return
+	}
+	p.Errors <- err
+}
+
+// splitEntry splits a single entry of a Bro log into its values, and
+// unescapes the separator in them. Only the raw separated tokens are
+// trimmed, when enabled.
+func (p *Parser) splitEntry(line string) []string {
+	separator := p.separator
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	entry := splitValues(line, separator, p.collapseSeparators)
+
+	// Values holding the separator have it escaped as \xNN
+	if strings.Contains(line, "\\x") {
+		escaped := encodeSeparator(separator)
+		for i, value := range entry {
+			entry[i] = strings.ReplaceAll(value, escaped, separator)
+		}
+	}
+
+	if p.trimValues {
+		for i, value := range entry {
+			entry[i] = strings.TrimSpace(value)
+		}
+	}
+
+	return entry
+}
+
 // Parse is used as an optional argument to BufferRow, and can be used
 // to perform additonal logic on the Bro log data.
 type Parse func([]string, []string) ([]string, error)
 
+// ParseWarning is returned by a Parse() function along with the row it
+// parsed, to have problems with the row reported (see CreateErrorBuffer)
+// while the parsed row is still used.
+type ParseWarning struct {
+	Errs []error
+}
+
+func (w *ParseWarning) Error() string {
+	if len(w.Errs) == 0 {
+		return "Parse warning"
+	}
+	return w.Errs[0].Error()
+}
+
+// ParseMulti is like Parse, but turns a row into any number of rows.
+// It is used as the argument to BufferRowMulti.
+type ParseMulti func([]string, []string) ([][]string, error)
+
+// eachEntry scans through the entries (data) of a Bro log and calls fn
+// with the line number and every entry, split into all of its values.
+// Header lines are parsed as they are encountered, so entries are always
+// split on the separator declared above them.
+// Scanning stops at the first error returned by fn.
+func (p *Parser) eachEntry(fn func(int, []string) error) error {
+
+	h := p.newHeader()
+
+	// A byte range starting after the header needs the header read first,
+	// and reading starts a byte early to know if start begins a line
+	var offset int64
+	if p.rangeStart > 0 {
+		var err error
+		if h, err = p.readHeader(); err != nil {
+			return err
+		}
+		offset = p.rangeStart - 1
+	}
+
+	file, fileErr := p.openAt(offset)
+	if fileErr != nil {
+		return fileErr
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	var tail *tailReader
+	if p.tolerateTail && isCompressed(p.filepath) {
+		tail = &tailReader{r: reader, corrupt: p.reportError}
+		reader = tail
+	}
+	if p.metrics != nil {
+		reader = &countingReader{r: reader, metrics: p.metrics}
+	}
+	if p.tee != nil {
+		reader = io.TeeReader(reader, p.tee)
+	}
+
+	// A deadline also ends waiting for more data of a followed log
+	var deadline time.Time
+	var timeout <-chan time.Time
+	if p.deadline > 0 {
+		deadline = time.Now().Add(p.deadline)
+		timer := time.NewTimer(p.deadline)
+		defer timer.Stop()
+		timeout = timer.C
+		reader = &deadlineReader{r: reader, deadline: deadline}
+	}
+	if p.stop != nil {
+		reader = &followReader{r: reader, pollInterval: p.follow, stop: p.stop, timeout: timeout}
+	}
+
+	// Decoding comes last, since a decoder doesn't read again after an EOF
+	reader = p.decodeReader(reader)
+
+	// A byte range starting after the header is in the block of the header
+	// read first, so that a block starting in the range is checked against it
+	p.useHeader(h)
+	p.headerBlocks = 0
+	if p.rangeStart > 0 && h.fields != nil {
+		p.headerBlocks = 1
+		p.blockFields = h.fields
+	}
+
+	var lineNum int
+	var lastTs time.Time
+
+	scanner, splitter := p.newScanner(reader)
+	for p.profile.scan(scanner) {
+		line := scanner.Text()
+		lineNum++
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return ErrDeadlineExceeded
+		}
+
+		if p.rangeEnd > 0 {
+			// The first line is the end of a line starting before the range
+			if offset > 0 && lineNum == 1 {
+				continue
+			}
+			if offset+splitter.start >= p.rangeEnd {
+				break
+			}
+		}
+
+		if line == "" {
+			continue
+		}
+
+		// Any line without a # is a row with values
+		if line[0] == '#' {
+			if err := h.parseLine(line); err != nil {
+				return err
+			}
+			p.useHeader(h)
+			if key, _ := h.directive(line); key == "fields" {
+				p.headerBlocks++
+				p.blockFields = h.fields
+			}
+			if p.headerCallback != nil {
+				p.headerCallback(h.directive(line))
+			}
+			continue
+		}
+
+		// The last line before a corrupt tail is cut off, however many
+		// values it happens to have
+		if splitter.unterminated && tail.corrupted() {
+			p.reportError(&TruncatedError{Line: lineNum, Entry: line})
+			p.metrics.addMalformedRow()
+			continue
+		}
+
+		// Lets make sure the value row is not malformed
+		if line[1:] == "" {
+			p.metrics.addMalformedRow()
+			continue
+		}
+
+		splitStart := p.profile.start()
+		var entry []string
+		if line[0] == '{' {
+			var err error
+			entry, err = p.jsonEntry(h, lineNum, line)
+			if err != nil {
+				if splitter.unterminated {
+					p.reportError(&TruncatedError{Line: lineNum, Entry: line})
+				}
+				p.metrics.addMalformedRow()
+				continue
+			}
+		} else {
+			entry = p.checkColumns(h, lineNum, p.splitEntry(line))
+		}
+		p.profile.add(profileSplit, splitStart)
+
+		// A last line without a newline is only complete if no values are missing
+		if splitter.unterminated && len(entry) < p.entryLen(h) {
+			p.reportError(&TruncatedError{Line: lineNum, Entry: line})
+			p.metrics.addMalformedRow()
+			continue
+		}
+
+		if p.checkOrder {
+			lastTs = p.checkEntryOrder(h, lineNum, entry, lastTs)
+		}
+
+		p.blockPath = h.path
+		if err := fn(lineNum, entry); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// entryLen returns the number of values an entry should have.
+func (p *Parser) entryLen(h *header) int {
+	if h.fields != nil {
+		return len(h.fields)
+	}
+	if p.allFields {
+		return len(p.fields)
+	}
+	return 0
+}
+
+// eachRow calls fn with every row that BufferRow would push into p.Row,
+// before any Parse() functions are applied. Rows contain either all the
+// fields of the Bro log or only the specific fields to be parsed.
+func (p *Parser) eachRow(fn func(int, []string) error) error {
+
+	if p.fields == nil {
+		return errors.New("No fields parsed")
+	}
+
+	// All fields in a preferred order are selected like specific fields
+	p.applyFieldOrder()
+	selectFields := p.allFields == false || p.fieldOrder != nil
+
+	if selectFields {
+		err := p.GetIndexOfFields()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Logs merged with cat repeat their header, possibly with other fields
+	block := 1
+	skipBlock := false
+
+	return p.eachEntry(func(lineNum int, entry []string) error {
+
+		if p.headerBlocks > block {
+			block = p.headerBlocks
+			skipBlock = p.checkHeaderBlock(lineNum) != nil
+		}
+		if skipBlock {
+			return nil
+		}
+
+		// Do we have specific fields we want to parse
+		if selectFields {
+			if p.allFields && len(p.fields) != len(entry) {
+				p.metrics.addMalformedRow()
+				return nil
+			}
+			var parsedEntry []string
+			for _, fieldIndex := range p.fieldsIndex {
+				if fieldIndex >= len(entry) {
+					p.metrics.addMalformedRow()
+					return nil
+				}
+				parsedEntry = append(parsedEntry, entry[fieldIndex])
+			}
+			return fn(lineNum, parsedEntry)
+		}
+
+		// Skip this line if columns and values don't match
+		if len(p.fields) != len(entry) {
+			p.metrics.addMalformedRow()
+			return nil
+		}
+		return fn(lineNum, entry)
+	})
+}
+
+// checkHeaderBlock validates the fields of a repeated header block against
+// the fields to be parsed, ex: in logs rotated and merged with cat. When
+// parsing specific fields, they are looked up again in the fields of the
+// block. The entries of blocks that don't match should be skipped.
+func (p *Parser) checkHeaderBlock(lineNum int) error {
+
+	var err error
+	if p.allFields {
+		blockFields := p.blockFields
+		if p.fieldOrder != nil {
+			blockFields = orderFields(blockFields, p.fieldOrder)
+		}
+		if !equalFields(blockFields, p.fields) {
+			err = errors.New("Header block before line " + strconv.Itoa(lineNum) + " has different fields, its entries are skipped")
+		} else if p.fieldOrder != nil {
+			p.fieldsIndex, err = p.indexOfFields(p.blockFields)
+		}
+	} else {
+		var fieldsIndex []int
+		fieldsIndex, err = p.indexOfFields(p.blockFields)
+		if err == nil {
+			p.fieldsIndex = fieldsIndex
+		} else {
+			err = errors.New("Header block before line " + strconv.Itoa(lineNum) + " is missing fields, its entries are skipped: " + err.Error())
+		}
+	}
+
+	if err != nil {
+		p.reportError(err)
+	}
+	return err
+}
+
+// equalFields reports whether two sets of fields are the same, in order.
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // BufferRow parses throught the entries (data) of a Bro log,
 // pushes them into the channel p.Row. There are two options
 // to configure what will be pushed into p.Row.
@@ -206,88 +879,104 @@ func (p *Parser) BufferRow(parseFunc ...Parse) {
 		return
 	}
 
-	if p.fields == nil {
-		fmt.Println("No fields parsed")
-		return
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		p.waitIfPaused()
+		p.Row <- p.applyParse(row, parseFunc)
+		return nil
+	})
+	if err != nil {
+		p.reportError(err)
 	}
 
-	if p.allFields == false {
-		err := p.GetIndexOfFields()
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+	close(p.Row)
+	if p.Errors != nil {
+		close(p.Errors)
 	}
+}
 
-	var moreDataFiltering bool
-	if len(parseFunc) == 0 {
-		moreDataFiltering = false
-	} else {
-		moreDataFiltering = true
-	}
+// BufferRowMulti is BufferRow, but every row is turned into the rows
+// returned by parseFunc, which are all pushed into p.Row. If parseFunc
+// fails, the raw row is pushed instead.
+func (p *Parser) BufferRowMulti(parseFunc ParseMulti) {
 
-	file, fileErr := os.Open(p.filepath)
-	if fileErr != nil {
-		fmt.Println(fileErr)
+	if p.Row == nil {
+		fmt.Println("Initialize nil channel, via CreateBuffer()")
 		return
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
 
-		// Any line without a # is a row with values
-		if string(line[0]) != "#" {
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		rows, parseErr := parseFunc(p.fields, row)
+		if parseErr != nil {
+			rows = [][]string{row}
+		}
 
-			// Lets make sure the value row is not malformed
-			if line[1:] == "" {
-				continue
-			}
+		for _, modifiedRow := range rows {
+			p.waitIfPaused()
+			p.Row <- p.output(row, modifiedRow)
+		}
+		return nil
+	})
+	if err != nil {
+		p.reportError(err)
+	}
 
-			entry := strings.Split(line, "\t")
+	close(p.Row)
+	if p.Errors != nil {
+		close(p.Errors)
+	}
+}
 
-			// Do we have specific fields we want to parse
-			if p.allFields == false {
-				var parsedEntry []string
-				for _, fieldIndex := range p.fieldsIndex {
-					parsedEntry = append(parsedEntry, entry[fieldIndex])
-				}
+// applyParse performs the extra data manipulation of the Parse() functions
+// on a row, in order, each one on the row returned by the one before. If
+// any of them fails, the raw row is kept. The row is then prepared for
+// output.
+func (p *Parser) applyParse(row []string, parseFunc []Parse) []string {
+
+	modifiedRow := row
+
+	// Do we want extra data manipulation
+	for i, fn := range parseFunc {
+		parseStart := p.profile.start()
+		parsedRow, err := fn(p.fields, modifiedRow)
+		p.profile.addParse(i, parseStart)
+		if warning, ok := err.(*ParseWarning); ok && parsedRow != nil {
+			for _, warningErr := range warning.Errs {
+				p.reportError(warningErr)
+			}
+			err = nil
+		}
+		if err != nil {
+			modifiedRow = row
+			break
+		}
+		modifiedRow = parsedRow
+	}
 
-				// Do we just want the raw entries
-				if moreDataFiltering == false {
-					p.Row <- parsedEntry
-				} else {
-					modifiedParsedEntry, err := parseFunc[0](p.fields, parsedEntry)
-					if err != nil {
-						p.Row <- parsedEntry
-					} else {
-						p.Row <- modifiedParsedEntry
-					}
+	outputStart := p.profile.start()
+	defer p.profile.add(profileOutput, outputStart)
+	return p.output(row, modifiedRow)
+}
 
-				}
-			} else {
-				// Skip this line if columns and values don't match
-				if len(p.fields) != len(entry) {
-					continue
-				}
-				// Do we just want the raw entries
-				if moreDataFiltering == false {
-					p.Row <- entry
-				} else {
-					modifiedParsedEntry, err := parseFunc[0](p.fields, entry)
-					if err != nil {
-						p.Row <- entry
-					} else {
-						p.Row <- modifiedParsedEntry
-					}
-				}
+// ReadAll parses every entry of the Bro log into a slice, in a single call
+// and without the need for a channel. Rows are the same as the ones BufferRow
+// pushes into p.Row, including the extra data manipulation of a Parse()
+// function. This is the simplest option for logs that fit in memory. When
+// SetMaxKeys is set, reading ends with a *MaxKeysError once more rows than
+// the bound are read.
+func (p *Parser) ReadAll(parseFunc ...Parse) ([][]string, error) {
 
-			}
+	var rows [][]string
 
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		rows = append(rows, p.applyParse(row, parseFunc))
+		if p.exceedsMaxKeys(len(rows)) {
+			return &MaxKeysError{Operation: "ReadAll", MaxKeys: p.maxKeys, Stopped: true}
 		}
-
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	close(p.Row)
+	return rows, nil
 }
@@ -1,7 +1,11 @@
 package parse
 
 import (
+	"bytes"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -109,3 +113,270 @@ func TestFieldsToUnderscore(t *testing.T) {
 	assert.Equal(underScoreFields[4], "id_resp_h", "parsed fields incorrectly")
 	assert.Equal(underScoreFields[5], "id_resp_p", "parsed fields incorrectly")
 }
+
+func TestTrimValues(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/padded.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.SetTrimValues(true)
+
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows int
+	for row := range parser.Row {
+		assert.Equal(row[0], "1452684903.908400", "trimmed entries incorrectly")
+		assert.Equal(row[1], "CbOiIv2wbbH7F25W21", "trimmed entries incorrectly")
+		assert.Equal(row[2], "10.1.20.227", "trimmed entries incorrectly")
+		assert.Equal(row[3], "tcp", "trimmed entries incorrectly")
+		rows++
+	}
+	assert.Equal(rows, 1, "parsed wrong number of entries")
+}
+
+func TestParserFS(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"logs/conn.log": &fstest.MapFile{
+			Data: []byte("#separator \\x09\n" +
+				"#fields\tts\tuid\tproto\n" +
+				"#types\ttime\tstring\tenum\n" +
+				"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+				"1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n"),
+		},
+	}
+
+	_, err := NewParserFS(fsys, "logs/missing.log", true)
+	assert.NotNil(err, "expected an error for a missing log")
+
+	parser, err := NewParserFS(fsys, "logs/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "proto"}, "parsed fields incorrectly")
+
+	parser.SetFields(fields)
+
+	err = parser.AutoCreateBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go parser.BufferRow()
+
+	var protos []string
+	for row := range parser.Row {
+		protos = append(protos, row[2])
+	}
+	assert.Equal(protos, []string{"tcp", "udp"}, "parsed entries incorrectly")
+}
+
+func TestHeaderlessLog(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/headerless.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parser.ParseAllFields()
+	assert.NotNil(err, "expected an error for a log without a #fields header")
+
+	parser.SetFields([]string{"ts", "uid", "id.orig_h", "proto"})
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+	assert.Equal(len(rows), 2, "parsed wrong number of entries")
+	assert.Equal(rows[1][3], "udp", "parsed entries incorrectly")
+
+	count, err := parser.Cardinality("id.orig_h")
+	assert.Nil(err)
+	assert.Equal(count, 2, "cardinality should use the fields that were set")
+}
+
+func TestCount(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields([]string{"uid", "proto"})
+
+	count, err := parser.Count(func(row []string) bool {
+		return row[1] == "tcp"
+	})
+	assert.Nil(err)
+	assert.Equal(count, 2, "counted matching rows incorrectly")
+
+	count, err = parser.Count(nil)
+	assert.Nil(err)
+	assert.Equal(count, 4, "counted all rows incorrectly")
+
+	// Only the rows BufferRow would push are counted
+	parser.SetFilter(func(row []string) bool {
+		return row[1] == "udp"
+	})
+	count, err = parser.Count(nil)
+	assert.Nil(err)
+	assert.Equal(count, 1, "expected the filter to apply")
+
+	parser.SetFilter(nil)
+	parser.SetLimit(1)
+	count, err = parser.Count(nil)
+	assert.Nil(err)
+	assert.Equal(count, 1, "expected the limit to apply")
+}
+
+func TestNewParserAuto(t *testing.T) {
+	assert := assert.New(t)
+
+	fieldSets := map[string][]string{
+		"conn": {"ts", "proto"},
+		"dns":  {"ts", "uid"},
+	}
+
+	parser, err := NewParserAuto(logpath, fieldSets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(parser.Fields(), []string{"ts", "proto"}, "picked the wrong field set")
+
+	parser.CreateBuffer(10)
+	go parser.BufferRow()
+
+	for row := range parser.Row {
+		assert.Equal(row, []string{"1452684903.908400", "tcp"}, "parsed entries incorrectly")
+	}
+
+	// Log types without a field set are parsed with all of their fields
+	parser, err = NewParserAuto(logpath, map[string][]string{"dns": {"ts"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(len(parser.Fields()), 8, "expected all fields to be parsed")
+}
+
+func TestFieldNameTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields([]string{"TS", "orig_h", "Proto"})
+	parser.SetFieldNameTransform(func(field string) string {
+		return strings.ToLower(strings.TrimPrefix(field, "id."))
+	})
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+	assert.Equal(rows, [][]string{{"1452684903.908400", "10.1.20.227", "tcp"}}, "transformed fields matched incorrectly")
+}
+
+func TestReadAll(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields([]string{"uid", "proto"})
+
+	upper := func(fields, row []string) ([]string, error) {
+		return []string{row[0], strings.ToUpper(row[1])}, nil
+	}
+
+	rows, err := parser.ReadAll(upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(rows, [][]string{
+		{"CbOiIv2wbbH7F25W21", "TCP"},
+		{"C7fIlMZDuRiqjpYbb", "UDP"},
+		{"CHhAvVGS1DHFjwGM9", "TCP"},
+		{"ClEkJM2Vm5giqnMf4h", "ICMP"},
+	}, "read rows incorrectly")
+
+	parser.SetFields([]string{"not_a_field"})
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected an error for an unknown field")
+}
+
+func TestFieldsFromList(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := FieldsFromList("uid, proto,,id.orig_h ")
+	assert.Equal(fields, []string{"uid", "proto", "id.orig_h"}, "split the list of fields incorrectly")
+
+	parser, err := NewParser(logpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetAllFields(false)
+	parser.SetFields(fields)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"CbOiIv2wbbH7F25W21", "tcp", "10.1.20.227"}}, "expected only the fields of the list")
+}
+
+func TestSetTee(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	var archive bytes.Buffer
+	parser.SetTee(&archive)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 4, "parsed the log incorrectly")
+
+	original, err := os.ReadFile("../sample_logs/unordered.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(archive.String(), string(original), "expected the log to be copied as read")
+}
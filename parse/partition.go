@@ -0,0 +1,49 @@
+package parse
+
+import "errors"
+
+// PartitionOverflowKey is the key Partition routes the rows of new keys
+// with, once SetMaxKeys is exceeded.
+const PartitionOverflowKey = "_overflow"
+
+// Partition routes every row to fn, along with the value of field in the
+// row as its key, ex: to fan rows out to a stream per proto or per sensor.
+// Rows are the same as the ones BufferRow pushes, and field must be one of
+// the fields parsed. The keys don't need to be known up front, and the keys
+// of addr fields are canonicalized, see CanonicalizeAddr. When SetMaxKeys is
+// set and exceeded, the rows of keys not seen yet are routed with
+// PartitionOverflowKey instead, so that fn is called with a bounded number
+// of keys.
+func (p *Parser) Partition(field string, fn func(key string, row []string)) error {
+
+	if p.fields == nil {
+		return errors.New("No fields parsed")
+	}
+
+	index, err := getIndex(p.fields, field)
+	if err != nil {
+		return err
+	}
+
+	isAddr := p.isAddrField(index)
+	keys := make(map[string]bool)
+	var overMaxKeys bool
+	return p.eachSelectedRow(func(lineNum int, row []string) error {
+		key := groupKey(isAddr, row[index])
+
+		// Past the bound, the keys not seen yet are routed as one
+		if p.maxKeys > 0 && !keys[key] {
+			if p.exceedsMaxKeys(len(keys) + 1) {
+				key = PartitionOverflowKey
+				if !overMaxKeys {
+					overMaxKeys = true
+					p.reportError(&MaxKeysError{Operation: "Partition by " + field, MaxKeys: p.maxKeys})
+				}
+			} else {
+				keys[key] = true
+			}
+		}
+		fn(key, p.output(row, row))
+		return nil
+	})
+}
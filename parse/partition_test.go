@@ -0,0 +1,30 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartition(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+
+	partitions := make(map[string][][]string)
+	err = parser.Partition("proto", func(key string, row []string) {
+		partitions[key] = append(partitions[key], row)
+	})
+	assert.Nil(err)
+
+	assert.Equal(len(partitions), 3, "expected a partition per proto")
+	assert.Equal(len(partitions["tcp"]), 2, "expected both tcp rows in their partition")
+	assert.Equal(partitions["udp"][0][1], "udp", "routed rows incorrectly")
+
+	err = parser.Partition("service", func(key string, row []string) {})
+	assert.NotNil(err, "expected an error for a field not parsed")
+}
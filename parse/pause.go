@@ -0,0 +1,42 @@
+package parse
+
+// Pause stops BufferRow from pushing rows into p.Row until Resume is called,
+// ex: while a downstream service is down. The position in the Bro log is
+// kept, so no rows are lost or pushed twice. When following the log, new
+// data keeps being waited for, and Stop also resumes the parse.
+func (p *Parser) Pause() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+// Resume continues a parse stopped by Pause.
+func (p *Parser) Resume() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+// waitIfPaused blocks while the parser is paused.
+func (p *Parser) waitIfPaused() {
+	p.pauseMu.Lock()
+	resumed := p.resumed
+	p.pauseMu.Unlock()
+
+	if resumed == nil {
+		return
+	}
+
+	// p.stop is nil, and never ready, when not following
+	select {
+	case <-resumed:
+	case <-p.stop:
+	}
+}
@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseResume(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "conn.log")
+	err := os.WriteFile(path, []byte(followHeader+"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields(fields)
+	parser.SetFollow(5 * time.Millisecond)
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+
+	row := <-parser.Row
+	assert.Equal(row[1], "CbOiIv2wbbH7F25W21", "parsed entries incorrectly")
+
+	parser.Pause()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n")
+	file.Close()
+
+	select {
+	case <-parser.Row:
+		t.Fatal("no rows should be pushed while paused")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	parser.Resume()
+
+	row = <-parser.Row
+	assert.Equal(row[1], "C7fIlMZDuRiqjpYbb", "expected the row written while paused")
+
+	parser.Pause()
+	parser.Stop()
+
+	_, open := <-parser.Row
+	assert.False(open, "Stop should end a paused parse")
+}
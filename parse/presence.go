@@ -0,0 +1,18 @@
+package parse
+
+// SetFieldPresence makes every row of Results come with which of its values
+// are set, in Result.Present, ex: to measure how often each field is
+// filled in. Empty values, ex: (empty) sets, are set.
+func (p *Parser) SetFieldPresence(enabled bool) {
+	p.fieldPresence = enabled
+}
+
+// presence returns whether each value of an emitted row is set.
+func (p *Parser) presence(row []string) []bool {
+	unset := p.unsetValue()
+	present := make([]bool, len(row))
+	for i, value := range row {
+		present[i] = value != unset
+	}
+	return present
+}
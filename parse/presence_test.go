@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFieldPresence(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tservice\ttunnel_parents\n" +
+		"#types\taddr\tstring\tset[string]\n" +
+		"10.1.20.227\tdns\t(empty)\n" +
+		"10.1.20.228\t-\t-\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	for result := range parser.Results() {
+		assert.Nil(result.Present, "expected no presence by default")
+	}
+
+	parser.SetFieldPresence(true)
+	var present [][]bool
+	for result := range parser.Results() {
+		present = append(present, result.Present)
+	}
+	assert.Equal(present, [][]bool{{true, true, true}, {true, false, false}}, "expected unset values to be missing, and empty ones present")
+}
@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// probeSize is how many bytes at the start of a Bro log are sniffed.
+const probeSize = 512
+
+// Probe checks that the file looks like a Bro log: text starting with a
+// # header line, or with the first object of a Zeek JSON log. It only
+// reads the start of the file, so that a pcap or other binary file given
+// by mistake fails fast instead of being parsed into garbage. Parsers don't
+// probe the log themselves, since probing a pipe or FIFO would use up the
+// start of its data.
+func (p *Parser) Probe() error {
+
+	start, err := p.sniff()
+	if err != nil {
+		return err
+	}
+
+	// Values of a log starting with a header may hold control bytes, ex:
+	// NUL bytes, to be handled by SetControlBytes
+	header := bytes.TrimLeft(start, " \t\r\n")
+	if len(header) > 0 && (header[0] == '#' || header[0] == '{') {
+		return nil
+	}
+
+	if err := checkText(start); err != nil {
+		return err
+	}
+	return errors.New("Not a Bro log, it has no header")
+}
+
+// sniff returns the first bytes of the Bro log.
+func (p *Parser) sniff() ([]byte, error) {
+
+	file, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	start := make([]byte, probeSize)
+	n, err := io.ReadFull(file, start)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	return start[:n], nil
+}
+
+// checkText returns an error if the start of a file isn't text. Text that
+// isn't UTF-8 is only binary with control bytes, so that logs in an 8-bit
+// encoding, ex: Latin-1 (see SetEncoding), aren't rejected.
+func checkText(start []byte) error {
+
+	// The last rune may be cut off by the end of the sniffed bytes
+	if len(start) == probeSize {
+		for i := len(start) - 1; i >= len(start)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(start[i]) {
+				if !utf8.FullRune(start[i:]) {
+					start = start[:i]
+				}
+				break
+			}
+		}
+	}
+
+	if bytes.IndexByte(start, 0) >= 0 || (!utf8.Valid(start) && hasControlBytes(start)) {
+		return errors.New("Not a Bro log, it looks like a binary file")
+	}
+	return nil
+}
+
+// hasControlBytes returns whether text has control bytes other than tabs
+// and line endings.
+func hasControlBytes(text []byte) bool {
+	for _, b := range text {
+		if isControlByte(rune(b)) && b != '\t' && b != '\n' && b != '\r' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbe(t *testing.T) {
+	assert := assert.New(t)
+
+	pcap := []byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00}
+	fsys := fstest.MapFS{
+		"trace.pcap":     {Data: pcap},
+		"conn.log":       {Data: []byte(followHeader)},
+		"conn.json":      {Data: []byte(`{"ts":1452684903.9084,"uid":"CbOiIv2wbbH7F25W21"}` + "\n")},
+		"headerless.log": {Data: []byte("1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n")},
+	}
+
+	parser, err := NewParserFS(fsys, "trace.pcap", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualError(parser.Probe(), "Not a Bro log, it looks like a binary file", "expected binary files to be rejected")
+
+	for path, ok := range map[string]bool{"conn.log": true, "conn.json": true, "headerless.log": false} {
+		parser, err := NewParserFS(fsys, path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(parser.Probe() == nil, ok, "probed "+path+" incorrectly")
+	}
+
+	parser, err = NewParser("../sample_logs/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(parser.Probe(), "sample logs should not be rejected")
+
+	// A rune cut off by the end of the sniffed bytes is still text
+	start := append([]byte("#"), make([]byte, probeSize-2)...)
+	for i := 1; i < len(start); i++ {
+		start[i] = 'a'
+	}
+	assert.Nil(checkText(append(start, "é"[0])), "a cut off rune should not be binary")
+}
@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"bufio"
+	"time"
+)
+
+// ParseProfile is the time a parse spent in each of its stages, see
+// SetProfiling. Scan is reading and splitting the log into lines, Split is
+// splitting lines into values, Select is selecting rows and preparing them
+// (ex: filters, blocklists and field transforms), Parse is the time spent
+// in each Parse() function, in order, and Output is adding computed fields,
+// row IDs and the like. Total is the whole parse, including the time spent
+// waiting on consumers, ex: to read p.Row.
+type ParseProfile struct {
+	Scan   time.Duration
+	Split  time.Duration
+	Select time.Duration
+	Parse  []time.Duration
+	Output time.Duration
+	Total  time.Duration
+	Rows   int
+}
+
+// The stages of a ParseProfile.
+const (
+	profileScan = iota
+	profileSplit
+	profileSelect
+	profileOutput
+)
+
+// SetProfiling enables or disables timing the stages of every parse, ex:
+// to find which Parse() function of a chain is the bottleneck, or whether
+// the log is read slower than it is parsed. Timing adds a small cost to
+// every row, so it is off by default.
+func (p *Parser) SetProfiling(enabled bool) {
+	p.profiling = enabled
+	if !enabled {
+		p.profile = nil
+	}
+}
+
+// Profile returns the profile of the last parse, ex: once BufferRow is
+// done, or nil when profiling is disabled.
+func (p *Parser) Profile() *ParseProfile {
+	return p.profile
+}
+
+// startProfile starts the profile of a parse, and returns a function
+// ending it.
+func (p *Parser) startProfile() func() {
+	if !p.profiling {
+		return func() {}
+	}
+	p.profile = new(ParseProfile)
+	start := time.Now()
+	return func() { p.profile.Total = time.Since(start) }
+}
+
+// start returns the time a stage starts at.
+func (pp *ParseProfile) start() time.Time {
+	if pp == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// add adds the time since start to a stage.
+func (pp *ParseProfile) add(stage int, start time.Time) {
+	if pp == nil {
+		return
+	}
+
+	elapsed := time.Since(start)
+	switch stage {
+	case profileScan:
+		pp.Scan += elapsed
+	case profileSplit:
+		pp.Split += elapsed
+	case profileSelect:
+		pp.Select += elapsed
+	case profileOutput:
+		pp.Output += elapsed
+	}
+}
+
+// addParse adds the time since start to the Parse() function at index.
+func (pp *ParseProfile) addParse(index int, start time.Time) {
+	if pp == nil {
+		return
+	}
+	for len(pp.Parse) <= index {
+		pp.Parse = append(pp.Parse, 0)
+	}
+	pp.Parse[index] += time.Since(start)
+}
+
+// scan is scanner.Scan, timed as the Scan stage.
+func (pp *ParseProfile) scan(scanner *bufio.Scanner) bool {
+	if pp == nil {
+		return scanner.Scan()
+	}
+	start := time.Now()
+	ok := scanner.Scan()
+	pp.Scan += time.Since(start)
+	return ok
+}
+
+// emit calls fn with a selected row, leaving the time fn takes out of the
+// Select stage.
+func (pp *ParseProfile) emit(fn func(int, []string) error, lineNum int, row []string) error {
+	if pp == nil {
+		return fn(lineNum, row)
+	}
+	pp.Rows++
+	start := time.Now()
+	err := fn(lineNum, row)
+	pp.Select -= time.Since(start)
+	return err
+}
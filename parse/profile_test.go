@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetProfiling(t *testing.T) {
+	assert := assert.New(t)
+
+	log := followHeader +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n"
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(log)}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+	assert.Nil(parser.Profile(), "expected no profile by default")
+
+	parser.SetProfiling(true)
+	parser.CreateBuffer(10)
+
+	slow := func(fields []string, row []string) ([]string, error) {
+		time.Sleep(time.Millisecond)
+		return row, nil
+	}
+	noop := func(fields []string, row []string) ([]string, error) {
+		return row, nil
+	}
+
+	go parser.BufferRow(slow, noop)
+	for range parser.Row {
+	}
+
+	profile := parser.Profile()
+	if profile == nil {
+		t.Fatal("expected a profile once parsed")
+	}
+	assert.Equal(profile.Rows, 2, "expected every row emitted")
+	assert.Len(profile.Parse, 2, "expected a duration per Parse() function")
+	assert.True(profile.Parse[0] >= 2*time.Millisecond, "expected the time spent in the first Parse() function")
+	assert.True(profile.Parse[0] > profile.Parse[1], "expected the slow Parse() function to take longer")
+	assert.True(profile.Select >= 0, "expected emitting rows to be left out of selecting them")
+	assert.True(profile.Total >= profile.Scan+profile.Split+profile.Parse[0], "expected the total to cover every stage")
+
+	parser.SetProfiling(false)
+	assert.Nil(parser.Profile(), "expected no profile once disabled")
+}
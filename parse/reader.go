@@ -0,0 +1,58 @@
+package parse
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// NewParserReader returns a new parser that reads the Bro log from the
+// streams returned by open, ex: a network download. The log is read more
+// than once (ex: for its header and then for its rows), so open is called
+// for every pass and must return a new stream each time, starting at the
+// byte offset given. name identifies the log, and logs whose name ends in
+// .gz or .bz2 are decompressed.
+func NewParserReader(name string, open func(offset int64) (io.ReadCloser, error), allFields bool) (*Parser, error) {
+
+	p := new(Parser)
+	p.markers = DefaultFieldMarkers
+	p.filepath = name
+	p.opener = open
+	p.allFields = allFields
+	return p, nil
+}
+
+// gzipReadCloser closes both the gzip stream and the compressed stream.
+type gzipReadCloser struct {
+	*gzip.Reader
+	compressed io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.compressed.Close()
+}
+
+// isCompressed reports whether name has the extension of a compressed log.
+func isCompressed(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".bz2")
+}
+
+// decompress wraps the stream of a Bro log named name with a decompressor,
+// if its name has the extension of a compressed log. bzip2 logs are
+// decompressed by workers in parallel.
+func decompress(name string, r io.ReadCloser, workers int) (io.ReadCloser, error) {
+	if strings.HasSuffix(name, ".bz2") {
+		return newBzip2Reader(r, workers), nil
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return r, nil
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: gz, compressed: r}, nil
+}
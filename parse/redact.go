@@ -0,0 +1,100 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Redaction modes of a RedactionPolicy.
+const (
+	// RedactMask replaces values with "REDACTED".
+	RedactMask = "mask"
+	// RedactHash replaces values with the hex SHA-256 of the salt and value,
+	// so that redacted values can still be joined on.
+	RedactHash = "hash"
+	// RedactUnset replaces values with the unset value of the log.
+	RedactUnset = "unset"
+)
+
+// RedactionPolicy maps field names to the redaction mode of their values.
+// Ex, as TOML:
+//
+//	salt = "per agreement secret"
+//
+//	[fields]
+//	"id.orig_h" = "hash"
+//	"uid" = "unset"
+type RedactionPolicy struct {
+	Salt   string            `toml:"salt" json:"salt"`
+	Fields map[string]string `toml:"fields" json:"fields"`
+}
+
+// RedactParse returns a Parse() function redacting the fields of policy.
+// Unset values and fields not in the row are left as is.
+func (p *Parser) RedactParse(policy RedactionPolicy) (Parse, error) {
+	return redactParse(policy, p.markers.Unset)
+}
+
+// redactParse returns the Parse() function of RedactParse, for logs whose
+// unset value is unset.
+func redactParse(policy RedactionPolicy, unset string) (Parse, error) {
+
+	for field, mode := range policy.Fields {
+		switch mode {
+		case RedactMask, RedactHash, RedactUnset:
+		default:
+			return nil, errors.New("Unknown redaction mode for field " + field + ": " + mode)
+		}
+	}
+
+	return func(fields, row []string) ([]string, error) {
+
+		redacted := append([]string{}, row...)
+		for i, field := range fields {
+			mode, ok := policy.Fields[field]
+			if !ok || i >= len(redacted) || redacted[i] == unset {
+				continue
+			}
+
+			switch mode {
+			case RedactMask:
+				redacted[i] = "REDACTED"
+			case RedactHash:
+				sum := sha256.Sum256([]byte(policy.Salt + redacted[i]))
+				redacted[i] = hex.EncodeToString(sum[:])
+			case RedactUnset:
+				redacted[i] = unset
+			}
+		}
+
+		return redacted, nil
+	}, nil
+}
+
+// LoadRedactionPolicy reads a RedactionPolicy from a TOML file, or a JSON
+// file when its extension is .json, and returns its RedactParse() function,
+// for logs whose unset value is "-".
+func LoadRedactionPolicy(path string) (Parse, error) {
+
+	var policy RedactionPolicy
+
+	if filepath.Ext(path) == ".json" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, err
+		}
+	} else if _, err := toml.DecodeFile(path, &policy); err != nil {
+		return nil, err
+	}
+
+	return redactParse(policy, DefaultFieldMarkers.Unset)
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRedactionPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	policies := map[string]string{
+		"policy.toml": "salt = \"s\"\n\n[fields]\n\"id.orig_h\" = \"hash\"\nuid = \"unset\"\nquery = \"mask\"\n",
+		"policy.json": `{"salt": "s", "fields": {"id.orig_h": "hash", "uid": "unset", "query": "mask"}}`,
+		"bad.toml":    "[fields]\nuid = \"scramble\"\n",
+	}
+	for name, policy := range policies {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(policy), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sum := sha256.Sum256([]byte("s10.1.20.227"))
+	fields := []string{"uid", "id.orig_h", "query", "proto"}
+	row := []string{"CbOiIv2wbbH7F25W21", "10.1.20.227", "example.com", "udp"}
+
+	for _, name := range []string{"policy.toml", "policy.json"} {
+		redact, err := LoadRedactionPolicy(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		redacted, err := redact(fields, row)
+		assert.Nil(err)
+		assert.Equal(redacted, []string{"-", hex.EncodeToString(sum[:]), "REDACTED", "udp"}, "redacted incorrectly with "+name)
+		assert.Equal(row[0], "CbOiIv2wbbH7F25W21", "the raw row should not be modified")
+
+		redacted, _ = redact(fields, []string{"C1", "-", "example.com", "udp"})
+		assert.Equal(redacted[1], "-", "unset values should stay unset")
+	}
+
+	_, err := LoadRedactionPolicy(filepath.Join(dir, "bad.toml"))
+	assert.NotNil(err, "expected an error for an unknown mode")
+
+	_, err = LoadRedactionPolicy(filepath.Join(dir, "missing.toml"))
+	assert.NotNil(err, "expected an error for a missing policy")
+}
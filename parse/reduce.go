@@ -0,0 +1,97 @@
+package parse
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Reduce folds every row of the Bro log into a single value, starting from
+// initial, ex: the sum of bytes per subnet in a map. reducer returns the
+// accumulated value after each row, and the value after the last row is
+// returned. Rows are the same as the ones BufferRow pushes.
+func (p *Parser) Reduce(initial interface{}, reducer func(acc interface{}, row []string) interface{}) (interface{}, error) {
+
+	acc := initial
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		acc = reducer(acc, p.output(row, row))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// ReduceTyped is Reduce, with every row converted into Go values by
+// TypedRow. Computed fields aren't part of typed rows. Reducing stops at
+// the first row that can't be converted, and its error is returned.
+func (p *Parser) ReduceTyped(initial interface{}, reducer func(acc interface{}, row []interface{}) interface{}) (interface{}, error) {
+
+	acc := initial
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		typed, err := p.TypedRow(row)
+		if err != nil {
+			return errors.New("Line " + strconv.Itoa(lineNum) + ": " + err.Error())
+		}
+		acc = reducer(acc, typed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// ReduceEvery is Reduce, also calling flush with the accumulated value
+// every interval while reducing, and once more after the last row, ex: to
+// update a dashboard of a followed Bro log. Rows aren't reduced while flush
+// runs, so it may read the accumulated value, but shouldn't keep it once it
+// returns.
+func (p *Parser) ReduceEvery(initial interface{}, reducer func(acc interface{}, row []string) interface{}, interval time.Duration, flush func(acc interface{})) (interface{}, error) {
+
+	if interval <= 0 {
+		return nil, errors.New("Flush interval must be positive")
+	}
+
+	var mu sync.Mutex
+	acc := initial
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				flush(acc)
+				mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		row = p.output(row, row)
+		mu.Lock()
+		acc = reducer(acc, row)
+		mu.Unlock()
+		return nil
+	})
+	ticker.Stop()
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	flush(acc)
+	return acc, nil
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReduce(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tproto\torig_bytes\n" +
+		"#types\taddr\tenum\tcount\n" +
+		"10.1.20.227\ttcp\t100\n" +
+		"10.1.20.228\tudp\t-\n" +
+		"10.1.20.227\ttcp\t50\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	protos, err := parser.Reduce(map[string]int{}, func(acc interface{}, row []string) interface{} {
+		acc.(map[string]int)[row[1]]++
+		return acc
+	})
+	assert.Nil(err)
+	assert.Equal(protos, map[string]int{"tcp": 2, "udp": 1}, "reduced the rows incorrectly")
+
+	bytes, err := parser.ReduceTyped(uint64(0), func(acc interface{}, row []interface{}) interface{} {
+		if orig, ok := row[2].(uint64); ok {
+			return acc.(uint64) + orig
+		}
+		return acc
+	})
+	assert.Nil(err)
+	assert.Equal(bytes, uint64(150), "reduced the typed rows incorrectly")
+
+	parser.SetFields([]string{"id.orig_h", "proto", "orig_bytes"})
+	parser.RegisterTypeCoercer("enum", func(value string) (interface{}, error) {
+		return nil, errors.New("Not an enum")
+	})
+	_, err = parser.ReduceTyped(0, func(acc interface{}, row []interface{}) interface{} { return acc })
+	assert.NotNil(err, "expected an error for a row that can't be converted")
+}
+
+func TestReduceEvery(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "conn.log")
+	err := os.WriteFile(path, []byte(followHeader+"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFollow(5 * time.Millisecond)
+
+	flushed := make(chan int, 100)
+	go func() {
+		// Wait for the first row to be flushed, then write another one
+		for <-flushed < 1 {
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Error(err)
+		}
+		file.WriteString("1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n")
+		file.Close()
+
+		for <-flushed < 2 {
+		}
+		parser.Stop()
+	}()
+
+	count, err := parser.ReduceEvery(0, func(acc interface{}, row []string) interface{} {
+		return acc.(int) + 1
+	}, 10*time.Millisecond, func(acc interface{}) {
+		flushed <- acc.(int)
+	})
+	assert.Nil(err)
+	assert.Equal(count, 2, "reduced the rows incorrectly")
+	assert.Equal(<-flushed, 2, "expected the last value to be flushed")
+
+	_, err = parser.ReduceEvery(0, nil, 0, nil)
+	assert.EqualError(err, "Flush interval must be positive")
+}
@@ -0,0 +1,64 @@
+package parse
+
+// Result is a row of the Bro log, or an error found while parsing, see
+// Results. Line is the line of the row or of the error, 0 when the error
+// isn't about a single line. Present is whether each value of Row is set,
+// when enabled by SetFieldPresence.
+type Result struct {
+	Row     []string
+	Err     error
+	Line    int
+	Present []bool
+}
+
+// Results parses the Bro log in the background, and returns a channel of
+// both its rows and the errors found while parsing, in the order they are
+// found, so that consumers range over a single channel instead of reading
+// p.Row and p.Errors together. Rows are the same as the ones BufferRow
+// pushes. The channel is closed once parsing is done, after an error that
+// ends parsing if there is one.
+func (p *Parser) Results(parseFunc ...Parse) <-chan Result {
+
+	// Set before parsing starts, so that reportError never races with it
+	results := make(chan Result)
+	p.results = results
+
+	go func() {
+		defer func() {
+			p.results = nil
+			close(results)
+		}()
+
+		err := p.eachSelectedRow(func(lineNum int, row []string) error {
+			p.waitIfPaused()
+			result := Result{Row: p.applyParse(row, parseFunc), Line: lineNum}
+			if p.fieldPresence {
+				result.Present = p.presence(result.Row)
+			}
+			results <- result
+			return nil
+		})
+		if err != nil {
+			p.reportError(err)
+		}
+	}()
+
+	return results
+}
+
+// errorLine returns the line an error found while parsing is about.
+func errorLine(err error) int {
+	switch err := err.(type) {
+	case *ControlByteError:
+		return err.Line
+	case *FieldTransformError:
+		return err.Line
+	case *TruncatedError:
+		return err.Line
+	case *OrderError:
+		return err.Line
+	case *ColumnCountError:
+		return err.Line
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResults(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tid.orig_h\tproto\n" +
+		"#types\taddr\tenum\n" +
+		"10.1.20.227\ttcp\n" +
+		"10.1.20.228\tUDP\n" +
+		"10.1.20.229\ticmp\n" +
+		"10.1.20.230\ttcp\textra\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFieldTransforms(map[string]func(string) (string, error){
+		"proto": func(value string) (string, error) {
+			if value != strings.ToLower(value) {
+				return "", errors.New("Not lowercase")
+			}
+			return value, nil
+		},
+	})
+
+	var rows [][]string
+	var errs []Result
+	for result := range parser.Results() {
+		if result.Err != nil {
+			errs = append(errs, result)
+			continue
+		}
+		rows = append(rows, result.Row)
+	}
+
+	assert.Equal(len(rows), 3, "expected every row")
+	assert.Equal(rows[2], []string{"10.1.20.229", "icmp"}, "expected the rows in order")
+	if assert.Equal(len(errs), 2, "expected the errors of the transform and of the extra value") {
+		assert.Equal(errs[0].Line, 5, "expected the line of the error")
+		_, ok := errs[1].Err.(*ColumnCountError)
+		assert.True(ok, "expected a *ColumnCountError")
+		assert.Equal(errs[1].Line, 7, "expected the line of the entry with an extra value")
+	}
+}
@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// SetRetryOnReadError makes a failed read of the Bro log be retried up to
+// attempts times, waiting backoff between each, before the error ends
+// parsing. This helps with transient errors of network filesystems
+// (ex: NFS, S3 fuse mounts). EOF and missing files are never retried.
+func (p *Parser) SetRetryOnReadError(attempts int, backoff time.Duration) {
+	p.readRetries = attempts
+	p.readBackoff = backoff
+}
+
+// withRetries wraps the raw file of the Bro log to retry its reads, when
+// enabled. Retries happen below decompression, since gzip and bzip2
+// readers keep failing once a read of the compressed data failed.
+func (p *Parser) withRetries(file io.ReadCloser) io.ReadCloser {
+	if p.readRetries <= 0 {
+		return file
+	}
+	return &retryReader{r: file, attempts: p.readRetries, backoff: p.readBackoff}
+}
+
+// retryReader retries the reads of r that fail with a transient error.
+type retryReader struct {
+	r        io.ReadCloser
+	attempts int
+	backoff  time.Duration
+}
+
+func (r *retryReader) Close() error {
+	return r.r.Close()
+}
+
+func (r *retryReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	for attempt := 0; attempt < r.attempts && n == 0 && isTransientReadError(err); attempt++ {
+		time.Sleep(r.backoff)
+		n, err = r.r.Read(b)
+	}
+	return n, err
+}
+
+// isTransientReadError reports whether a read error may succeed if retried.
+func isTransientReadError(err error) bool {
+	if err == nil || err == io.EOF {
+		return false
+	}
+	return !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrClosed) && !errors.Is(err, fs.ErrPermission)
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyFS opens files whose reads fail a number of times before succeeding,
+// once failAt bytes are read.
+type flakyFS struct {
+	fsys     fstest.MapFS
+	failures int
+	failAt   int
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file, failures: f.failures, failAt: f.failAt}, nil
+}
+
+type flakyFile struct {
+	fs.File
+	failures int
+	failAt   int
+	read     int
+}
+
+func (f *flakyFile) Read(b []byte) (int, error) {
+	if f.failures > 0 {
+		if f.read >= f.failAt {
+			f.failures--
+			return 0, errors.New("Transient read error")
+		}
+		if f.read+len(b) > f.failAt {
+			b = b[:f.failAt-f.read]
+		}
+	}
+	n, err := f.File.Read(b)
+	f.read += n
+	return n, err
+}
+
+func TestRetryOnReadError(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := &flakyFS{fsys: fstest.MapFS{"conn.log": {Data: []byte(followHeader +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n")}}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+
+	fsys.failures = 4
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected the read error without retries")
+
+	fsys.failures = 2
+	parser.SetRetryOnReadError(2, time.Millisecond)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 1, "expected the row after retrying")
+
+	fsys.failures = 3
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected the read error once retries are exhausted")
+}
+
+func TestRetryOnReadErrorCompressed(t *testing.T) {
+	assert := assert.New(t)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(followHeader + strings.Repeat("1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n", 100)))
+	gz.Close()
+
+	// Reads fail in the middle of the compressed data, not at its start
+	fsys := &flakyFS{fsys: fstest.MapFS{"conn.log.gz": {Data: compressed.Bytes()}}, failAt: compressed.Len() / 2}
+
+	parser, err := NewParserFS(fsys, "conn.log.gz", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "uid", "proto"})
+
+	fsys.failures = 2
+	_, err = parser.ReadAll()
+	assert.NotNil(err, "expected the read error without retries")
+
+	parser.SetRetryOnReadError(2, time.Millisecond)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 100, "expected every row of the compressed log after retrying")
+}
@@ -0,0 +1,122 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reverseDNSRate is the most reverse lookups ReverseDNSParse makes a second.
+const reverseDNSRate = 50
+
+// reverseDNSCacheSize is the most addresses ReverseDNSParse caches.
+const reverseDNSCacheSize = 100000
+
+// lookupAddr performs a reverse lookup of an address, returning its names.
+var lookupAddr = net.DefaultResolver.LookupAddr
+
+// ReverseDNSParse returns a Parse() function that appends a column to the
+// row for each of ipFields (ex: "id.orig_h", "id.resp_h"), holding the
+// hostname of its address from a reverse (PTR) lookup. Lookups taking
+// longer than timeout, failing or returning no name, and unset values get
+// the unset value "-". Addresses recur constantly in Bro logs, so the
+// result of every lookup is cached for the life of the function, failures
+// included, and lookups are limited to 50 a second. The cache is emptied
+// every time it holds more than 100000 addresses.
+func ReverseDNSParse(ipFields []string, timeout time.Duration) Parse {
+
+	resolver := &reverseResolver{
+		timeout:  timeout,
+		interval: time.Second / reverseDNSRate,
+		names:    make(map[string]string),
+	}
+
+	return func(fields, row []string) ([]string, error) {
+
+		modifiedRow := append([]string{}, row...)
+		for _, ipField := range ipFields {
+			index, err := getIndex(fields, ipField)
+			if err != nil {
+				return nil, err
+			}
+			if index >= len(row) {
+				return nil, errors.New("Row is missing field: " + ipField)
+			}
+			name := resolver.hostname(row[index])
+			if name == "" {
+				name = DefaultFieldMarkers.Unset
+			}
+			modifiedRow = append(modifiedRow, name)
+		}
+
+		resolver.bound(reverseDNSCacheSize)
+		return modifiedRow, nil
+	}
+}
+
+// reverseResolver caches and rate limits reverse lookups.
+type reverseResolver struct {
+	timeout  time.Duration
+	interval time.Duration
+	mu       sync.Mutex
+	names    map[string]string
+	next     time.Time
+}
+
+// bound empties the cache once it holds more than size addresses.
+func (r *reverseResolver) bound(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.names) > size {
+		r.names = make(map[string]string)
+	}
+}
+
+// hostname returns the cached hostname of an address, looking it up first
+// if it hasn't been yet, or "" if it has none.
+func (r *reverseResolver) hostname(addr string) string {
+
+	if net.ParseIP(addr) == nil {
+		return ""
+	}
+
+	r.mu.Lock()
+	name, ok := r.names[addr]
+	r.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	r.wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	if names, err := lookupAddr(ctx, addr); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+
+	r.mu.Lock()
+	r.names[addr] = name
+	r.mu.Unlock()
+	return name
+}
+
+// wait blocks until the next lookup is allowed by the rate limit.
+func (r *reverseResolver) wait() {
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	time.Sleep(delay)
+}
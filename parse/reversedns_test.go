@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseDNSParse(t *testing.T) {
+	assert := assert.New(t)
+
+	lookups := map[string]int{}
+	defer func(original func(context.Context, string) ([]string, error)) { lookupAddr = original }(lookupAddr)
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		lookups[addr]++
+		switch addr {
+		case "10.1.20.227":
+			return []string{"workstation.example.com."}, nil
+		case "204.238.149.187":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, errors.New("no such host")
+	}
+
+	fields := []string{"id.orig_h", "id.resp_h"}
+	parseFunc := ReverseDNSParse([]string{"id.orig_h", "id.resp_h"}, 10*time.Millisecond)
+
+	row, err := parseFunc(fields, []string{"10.1.20.227", "204.238.149.187"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"10.1.20.227", "204.238.149.187", "workstation.example.com", "-"}, "expected a hostname column per address field")
+
+	row, err = parseFunc(fields, []string{"10.1.20.227", "-"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"10.1.20.227", "-", "workstation.example.com", "-"}, "expected unset addresses to be unset")
+
+	row, err = parseFunc(fields, []string{"10.1.20.228", "204.238.149.187"})
+	assert.Nil(err)
+	assert.Equal(row[2:], []string{"-", "-"}, "expected failed lookups to be unset")
+
+	assert.Equal(lookups, map[string]int{"10.1.20.227": 1, "204.238.149.187": 1, "10.1.20.228": 1}, "expected every address to be looked up once")
+
+	_, err = parseFunc([]string{"id.orig_h"}, []string{"10.1.20.227"})
+	assert.NotNil(err, "expected an error for an unknown field")
+}
+
+func TestReverseDNSCacheBound(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(original func(context.Context, string) ([]string, error)) { lookupAddr = original }(lookupAddr)
+	lookups := 0
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		lookups++
+		return []string{"host.example.com."}, nil
+	}
+
+	resolver := &reverseResolver{timeout: time.Second, names: make(map[string]string)}
+	for _, addr := range []string{"10.1.20.227", "10.1.20.228", "10.1.20.229", "10.1.20.227"} {
+		assert.Equal(resolver.hostname(addr), "host.example.com", "expected the hostname")
+		resolver.bound(2)
+	}
+	assert.Equal(lookups, 4, "expected the cache to be emptied past the bound")
+}
@@ -0,0 +1,171 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RotatingWriter writes rows to Bro logs covering a window of time each,
+// ex: an hour, the way Bro rotates its own logs. It is a Sink, see
+// NewRotatingWriter.
+type RotatingWriter struct {
+	p        *Parser
+	window   time.Duration
+	template string
+	header   *header
+	lines    []string
+	tsIndex  int
+
+	start time.Time
+	file  *os.File
+	out   *bufio.Writer
+}
+
+// NewRotatingWriter returns a writer of rows, such as the ones BufferRow
+// pushes, to a Bro log per window of time their ts fall in. Windows are
+// aligned on UTC, ex: on the hour, and a log is opened as rows cross into
+// a new window. Logs are named after template, with {start} and {end}
+// replaced by the times their window starts and ends at, ex:
+// "conn.{start}.log". Every log has the header of the Bro log being
+// parsed, with the fields parsed and with #open and #close set to its
+// window. Rows are mostly in ts order, as with any Bro log, but a log is
+// written as its connections end, so rows with a ts earlier than the
+// window being written are written to its log rather than reopening an
+// earlier one. The output separator applies.
+func (p *Parser) NewRotatingWriter(window time.Duration, template string) (*RotatingWriter, error) {
+
+	if window <= 0 {
+		return nil, errors.New("Window must be positive")
+	}
+	if !strings.Contains(template, "{start}") {
+		return nil, errors.New("Filename template must contain {start}")
+	}
+	if p.fields == nil {
+		return nil, errors.New("No fields parsed")
+	}
+
+	lines, h, err := p.headerLines()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkOutputSeparator(h); err != nil {
+		return nil, err
+	}
+	types, err := p.rowTypes()
+	if err != nil {
+		return nil, err
+	}
+	tsIndex, err := getIndex(p.fields, "ts")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &RotatingWriter{p: p, window: window, template: template, header: h, tsIndex: tsIndex}
+
+	// Header lines are rewritten with the separator in effect when read
+	lineHeader := &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+	var opened bool
+	for _, line := range lines {
+		if err := lineHeader.parseLine(line); err != nil {
+			return nil, err
+		}
+
+		switch key, _ := lineHeader.directive(line); key {
+		case "fields":
+			line = "#fields" + lineHeader.separator + strings.Join(p.fields, lineHeader.separator)
+		case "types":
+			line = "#types" + lineHeader.separator + strings.Join(types, lineHeader.separator)
+		case "open":
+			// #open is stamped with the window of every log
+			opened = true
+			w.lines = append(w.lines, "")
+			continue
+		case "close":
+			continue
+		}
+		w.lines = append(w.lines, p.outputHeaderLine(lineHeader, line))
+	}
+	if !opened {
+		w.lines = append(w.lines, "")
+	}
+
+	return w, nil
+}
+
+// Write writes a row to the log of the window its ts falls in, opening it
+// if the row is the first of its window. Late rows, earlier than the
+// window being written, are written to its log.
+func (w *RotatingWriter) Write(row []string) error {
+
+	if w.tsIndex >= len(row) {
+		return errors.New("Row has no ts")
+	}
+	ts, err := ParseTime(row[w.tsIndex])
+	if err != nil {
+		return err
+	}
+
+	start := ts.Truncate(w.window)
+	if w.file == nil || start.After(w.start) {
+		if err := w.rotate(start); err != nil {
+			return err
+		}
+	}
+
+	return w.p.writeLine(w.out, w.p.outputEntry(w.header, row))
+}
+
+// Close ends the log being written with its #close line.
+func (w *RotatingWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	w.p.writeLine(w.out, "#close"+w.p.outputSeparatorOf(w.header)+w.start.Add(w.window).Format(headerTimeFormat))
+	err := w.out.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	w.file = nil
+	return err
+}
+
+// rotate closes the log being written, and opens the log of the window
+// starting at start with its header.
+func (w *RotatingWriter) rotate(start time.Time) error {
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	separator := w.p.outputSeparatorOf(w.header)
+	path := strings.NewReplacer(
+		"{start}", start.Format(headerTimeFormat),
+		"{end}", start.Add(w.window).Format(headerTimeFormat),
+	).Replace(w.template)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w.start = start
+	w.file = file
+	w.out = bufio.NewWriter(file)
+	for _, line := range w.lines {
+		if line == "" {
+			line = "#open" + separator + start.Format(headerTimeFormat)
+		}
+		w.p.writeLine(w.out, line)
+	}
+
+	return nil
+}
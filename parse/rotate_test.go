@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#path\tconn\n" +
+		"#open\t2016-01-13-11-00-00\n" +
+		"#fields\tts\tuid\tproto\n" +
+		"#types\ttime\tstring\tenum\n" +
+		"1452682800.000000\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"1452684903.908400\tC7fIlMZDuRiqjpYbb\tudp\n" +
+		"1452686400.000000\tCxPe2flVpE5gerpQc4\ttcp\n" +
+		"#close\t2016-01-13-12-00-00\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "proto"})
+
+	dir := t.TempDir()
+	writer, err := parser.NewRotatingWriter(time.Hour, filepath.Join(dir, "2016", "conn.{start}.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.CreateBuffer(10)
+	go parser.BufferRow()
+	assert.Nil(Drain(parser.Row, writer))
+
+	first, err := os.ReadFile(filepath.Join(dir, "2016", "conn.2016-01-13-11-00-00.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(string(first), "#separator \\x09\n"+
+		"#path\tconn\n"+
+		"#open\t2016-01-13-11-00-00\n"+
+		"#fields\tts\tproto\n"+
+		"#types\ttime\tenum\n"+
+		"1452682800.000000\ttcp\n"+
+		"1452684903.908400\tudp\n"+
+		"#close\t2016-01-13-12-00-00\n", "expected the rows of the first hour")
+
+	second, err := os.ReadFile(filepath.Join(dir, "2016", "conn.2016-01-13-12-00-00.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(strings.HasPrefix(string(second), "#separator \\x09\n#path\tconn\n#open\t2016-01-13-12-00-00\n"), "expected #open set to the window")
+	assert.True(strings.HasSuffix(string(second), "1452686400.000000\ttcp\n#close\t2016-01-13-13-00-00\n"), "expected the rows of the second hour")
+
+	// Late rows are written to the window being written
+	writer, err = parser.NewRotatingWriter(time.Hour, filepath.Join(dir, "conn.{start}-{end}.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(writer.Write([]string{"1452686400.000000", "tcp"}))
+	assert.Nil(writer.Write([]string{"1452686399.500000", "udp"}), "expected a late row to be written")
+	assert.Nil(writer.Close())
+	late, err := os.ReadFile(filepath.Join(dir, "conn.2016-01-13-12-00-00-2016-01-13-13-00-00.log"))
+	assert.Nil(err, "expected {end} in the name of the log")
+	assert.True(strings.HasSuffix(string(late), "1452686400.000000\ttcp\n1452686399.500000\tudp\n#close\t2016-01-13-13-00-00\n"), "expected the late row in the log of the window")
+	_, err = os.Stat(filepath.Join(dir, "conn.2016-01-13-11-00-00-2016-01-13-12-00-00.log"))
+	assert.True(os.IsNotExist(err), "expected no log reopened for the late row")
+
+	_, err = parser.NewRotatingWriter(time.Hour, "conn.log")
+	assert.EqualError(err, "Filename template must contain {start}")
+}
@@ -0,0 +1,34 @@
+package parse
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// RowIDField is the name of the row ID field, see SetRowID.
+const RowIDField = "_row_id"
+
+// SetRowID enables or disables prepending a row ID to every row pushed by
+// BufferRow or returned by ReadAll. The ID is the RowID of the row before
+// any Parse() function is applied, in decimal, and gives consumers a
+// deterministic key to dedup and join rows on when there is no uid field.
+func (p *Parser) SetRowID(enabled bool) {
+	p.rowID = enabled
+}
+
+// RowID returns the FNV-64a hash of the values of a row. Rows with the
+// same values always have the same ID.
+func RowID(row []string) uint64 {
+	hasher := fnv.New64a()
+	for _, value := range row {
+		hasher.Write([]byte(value))
+		// Separate values so that ["ab", "c"] and ["a", "bc"] differ
+		hasher.Write([]byte{0})
+	}
+	return hasher.Sum64()
+}
+
+// withRowID returns rawRow's ID followed by the values of row.
+func withRowID(rawRow, row []string) []string {
+	return append([]string{strconv.FormatUint(RowID(rawRow), 10)}, row...)
+}
@@ -0,0 +1,40 @@
+package parse
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(RowID([]string{"a", "b"}), RowID([]string{"a", "b"}), "equal rows should have equal IDs")
+	assert.NotEqual(RowID([]string{"ab", "c"}), RowID([]string{"a", "bc"}), "values should not run together")
+
+	parser, err := NewParser(logpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	raw, err := parser.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetRowID(true)
+	rows, err := parser.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(rows[0][0], strconv.FormatUint(RowID(raw[0]), 10), "expected the row ID first")
+	assert.Equal(rows[0][1:], raw[0], "expected the values after the row ID")
+}
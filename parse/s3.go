@@ -0,0 +1,40 @@
+package parse
+
+import (
+	"context"
+	"io"
+)
+
+// S3Client gets objects from S3, and is how NewParserS3 reaches S3:
+// gobro doesn't depend on an AWS SDK, so the client, its credentials and
+// its region stay the caller's. GetObject returns the body of the object
+// starting at the byte offset given. With aws-sdk-go-v2, the client is a
+// small adapter of s3.Client:
+//
+//	type sdkClient struct{ client *s3.Client }
+//
+//	func (c sdkClient) GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error) {
+//		input := &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+//		if offset > 0 {
+//			input.Range = aws.String("bytes=" + strconv.FormatInt(offset, 10) + "-")
+//		}
+//		out, err := c.client.GetObject(ctx, input)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Body, nil
+//	}
+//
+// Any other S3 compatible client, ex: for MinIO, works the same way.
+type S3Client interface {
+	GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error)
+}
+
+// NewParserS3 returns a new parser that streams the Bro log stored in S3
+// under bucket and key, without downloading it first. Logs with a .gz or
+// .bz2 key are decompressed. Every pass over the log gets the object again.
+func NewParserS3(ctx context.Context, client S3Client, bucket, key string, allFields bool) (*Parser, error) {
+	return NewParserReader(key, func(offset int64) (io.ReadCloser, error) {
+		return client.GetObject(ctx, bucket, key, offset)
+	}, allFields)
+}
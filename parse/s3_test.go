@@ -0,0 +1,62 @@
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 serves objects from memory, recording the requests made.
+type fakeS3 struct {
+	objects  map[string][]byte
+	requests int
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, bucket, key string, offset int64) (io.ReadCloser, error) {
+	f.requests++
+	object, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return io.NopCloser(bytes.NewReader(object[offset:])), nil
+}
+
+func TestNewParserS3(t *testing.T) {
+	assert := assert.New(t)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(followHeader + "1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n"))
+	gz.Close()
+
+	client := &fakeS3{objects: map[string][]byte{"logs/2016-01-13/conn.log.gz": compressed.Bytes()}}
+
+	parser, err := NewParserS3(context.Background(), client, "logs", "2016-01-13/conn.log.gz", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(fields, []string{"ts", "uid", "proto"}, "read the header of the object incorrectly")
+
+	parser.SetFields(fields)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp"}}, "read the rows of the object incorrectly")
+	assert.Equal(client.requests, 2, "expected a request for every pass over the log")
+
+	parser, err = NewParserS3(context.Background(), client, "logs", "missing.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.ParseAllFields()
+	assert.NotNil(err, "expected an error for a missing object")
+}
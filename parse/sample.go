@@ -0,0 +1,30 @@
+package parse
+
+import (
+	"errors"
+)
+
+// SampleRows returns the fields parsed and the first n rows of the Bro log,
+// ex: to check that the header and the entries line up before a long parse,
+// or to preview a log. The log is only read up to the n-th row. Rows are
+// the same as the ones BufferRow pushes before any Parse() function.
+func (p *Parser) SampleRows(n int) ([][]string, []string, error) {
+
+	if n <= 0 {
+		return nil, nil, errors.New("Number of rows to sample must be positive")
+	}
+
+	rows := make([][]string, 0, n)
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		rows = append(rows, row)
+		if len(rows) >= n {
+			return errStopEmitting
+		}
+		return nil
+	})
+	if err != nil && err != errStopEmitting {
+		return nil, nil, err
+	}
+
+	return rows, p.fields, nil
+}
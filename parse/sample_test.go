@@ -0,0 +1,44 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleRows(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\tproto\n" +
+		"#types\ttime\taddr\tenum\n" +
+		"1300475167.096535\t10.1.20.227\ttcp\n" +
+		"1300475168.096535\t10.1.20.228\tudp\n" +
+		"1300475169.096535\t10.1.20.229\ticmp\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allFields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(allFields)
+
+	rows, fields, err := parser.SampleRows(2)
+	assert.Nil(err)
+	assert.Equal(fields, []string{"ts", "id.orig_h", "proto"}, "sampled the wrong fields")
+	assert.Equal(rows, [][]string{
+		{"1300475167.096535", "10.1.20.227", "tcp"},
+		{"1300475168.096535", "10.1.20.228", "udp"},
+	}, "sampled the wrong rows")
+
+	rows, _, err = parser.SampleRows(10)
+	assert.Nil(err)
+	assert.Equal(len(rows), 3, "expected every row of a short log")
+
+	_, _, err = parser.SampleRows(0)
+	assert.NotNil(err, "expected an error for no rows to sample")
+}
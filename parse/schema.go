@@ -0,0 +1,554 @@
+package parse
+
+import (
+	"errors"
+	"sort"
+)
+
+// schemaField is a single column of a known Bro log type.
+type schemaField struct {
+	name    string
+	broType string
+}
+
+// connID are the connection tuple fields shared by most log types.
+var connID = []schemaField{
+	{"id.orig_h", "addr"},
+	{"id.orig_p", "port"},
+	{"id.resp_h", "addr"},
+	{"id.resp_p", "port"},
+}
+
+// withConnID builds the fields of a log type that starts with ts, uid
+// and the connection tuple.
+func withConnID(fields ...schemaField) []schemaField {
+	schema := []schemaField{{"ts", "time"}, {"uid", "string"}}
+	schema = append(schema, connID...)
+	return append(schema, fields...)
+}
+
+// knownSchemas holds the default fields and types of the logs written by
+// a stock Bro/Zeek install, keyed by the value of #path.
+var knownSchemas = map[string][]schemaField{
+	"conn": withConnID(
+		schemaField{"proto", "enum"},
+		schemaField{"service", "string"},
+		schemaField{"duration", "interval"},
+		schemaField{"orig_bytes", "count"},
+		schemaField{"resp_bytes", "count"},
+		schemaField{"conn_state", "string"},
+		schemaField{"local_orig", "bool"},
+		schemaField{"local_resp", "bool"},
+		schemaField{"missed_bytes", "count"},
+		schemaField{"history", "string"},
+		schemaField{"orig_pkts", "count"},
+		schemaField{"orig_ip_bytes", "count"},
+		schemaField{"resp_pkts", "count"},
+		schemaField{"resp_ip_bytes", "count"},
+		schemaField{"tunnel_parents", "set[string]"},
+	),
+	"dns": withConnID(
+		schemaField{"proto", "enum"},
+		schemaField{"trans_id", "count"},
+		schemaField{"rtt", "interval"},
+		schemaField{"query", "string"},
+		schemaField{"qclass", "count"},
+		schemaField{"qclass_name", "string"},
+		schemaField{"qtype", "count"},
+		schemaField{"qtype_name", "string"},
+		schemaField{"rcode", "count"},
+		schemaField{"rcode_name", "string"},
+		schemaField{"AA", "bool"},
+		schemaField{"TC", "bool"},
+		schemaField{"RD", "bool"},
+		schemaField{"RA", "bool"},
+		schemaField{"Z", "count"},
+		schemaField{"answers", "vector[string]"},
+		schemaField{"TTLs", "vector[interval]"},
+		schemaField{"rejected", "bool"},
+	),
+	"http": withConnID(
+		schemaField{"trans_depth", "count"},
+		schemaField{"method", "string"},
+		schemaField{"host", "string"},
+		schemaField{"uri", "string"},
+		schemaField{"referrer", "string"},
+		schemaField{"version", "string"},
+		schemaField{"user_agent", "string"},
+		schemaField{"origin", "string"},
+		schemaField{"request_body_len", "count"},
+		schemaField{"response_body_len", "count"},
+		schemaField{"status_code", "count"},
+		schemaField{"status_msg", "string"},
+		schemaField{"info_code", "count"},
+		schemaField{"info_msg", "string"},
+		schemaField{"tags", "set[enum]"},
+		schemaField{"username", "string"},
+		schemaField{"password", "string"},
+		schemaField{"proxied", "set[string]"},
+		schemaField{"orig_fuids", "vector[string]"},
+		schemaField{"orig_filenames", "vector[string]"},
+		schemaField{"orig_mime_types", "vector[string]"},
+		schemaField{"resp_fuids", "vector[string]"},
+		schemaField{"resp_filenames", "vector[string]"},
+		schemaField{"resp_mime_types", "vector[string]"},
+	),
+	"ssl": withConnID(
+		schemaField{"version", "string"},
+		schemaField{"cipher", "string"},
+		schemaField{"curve", "string"},
+		schemaField{"server_name", "string"},
+		schemaField{"resumed", "bool"},
+		schemaField{"last_alert", "string"},
+		schemaField{"next_protocol", "string"},
+		schemaField{"established", "bool"},
+		schemaField{"cert_chain_fuids", "vector[string]"},
+		schemaField{"client_cert_chain_fuids", "vector[string]"},
+		schemaField{"subject", "string"},
+		schemaField{"issuer", "string"},
+		schemaField{"client_subject", "string"},
+		schemaField{"client_issuer", "string"},
+		schemaField{"validation_status", "string"},
+	),
+	"files": {
+		{"ts", "time"},
+		{"fuid", "string"},
+		{"tx_hosts", "set[addr]"},
+		{"rx_hosts", "set[addr]"},
+		{"conn_uids", "set[string]"},
+		{"source", "string"},
+		{"depth", "count"},
+		{"analyzers", "set[string]"},
+		{"mime_type", "string"},
+		{"filename", "string"},
+		{"duration", "interval"},
+		{"local_orig", "bool"},
+		{"is_orig", "bool"},
+		{"seen_bytes", "count"},
+		{"total_bytes", "count"},
+		{"missing_bytes", "count"},
+		{"overflow_bytes", "count"},
+		{"timedout", "bool"},
+		{"parent_fuid", "string"},
+		{"md5", "string"},
+		{"sha1", "string"},
+		{"sha256", "string"},
+		{"extracted", "string"},
+		{"extracted_cutoff", "bool"},
+		{"extracted_size", "count"},
+	},
+	"dhcp": {
+		{"ts", "time"},
+		{"uids", "set[string]"},
+		{"client_addr", "addr"},
+		{"server_addr", "addr"},
+		{"mac", "string"},
+		{"host_name", "string"},
+		{"client_fqdn", "string"},
+		{"domain", "string"},
+		{"requested_addr", "addr"},
+		{"assigned_addr", "addr"},
+		{"lease_time", "interval"},
+		{"client_message", "string"},
+		{"server_message", "string"},
+		{"msg_types", "vector[string]"},
+		{"duration", "interval"},
+	},
+	"ssh": withConnID(
+		schemaField{"version", "count"},
+		schemaField{"auth_success", "bool"},
+		schemaField{"auth_attempts", "count"},
+		schemaField{"direction", "enum"},
+		schemaField{"client", "string"},
+		schemaField{"server", "string"},
+		schemaField{"cipher_alg", "string"},
+		schemaField{"mac_alg", "string"},
+		schemaField{"compression_alg", "string"},
+		schemaField{"kex_alg", "string"},
+		schemaField{"host_key_alg", "string"},
+		schemaField{"host_key", "string"},
+	),
+	"smtp": withConnID(
+		schemaField{"trans_depth", "count"},
+		schemaField{"helo", "string"},
+		schemaField{"mailfrom", "string"},
+		schemaField{"rcptto", "set[string]"},
+		schemaField{"date", "string"},
+		schemaField{"from", "string"},
+		schemaField{"to", "set[string]"},
+		schemaField{"cc", "set[string]"},
+		schemaField{"reply_to", "string"},
+		schemaField{"msg_id", "string"},
+		schemaField{"in_reply_to", "string"},
+		schemaField{"subject", "string"},
+		schemaField{"x_originating_ip", "addr"},
+		schemaField{"first_received", "string"},
+		schemaField{"second_received", "string"},
+		schemaField{"last_reply", "string"},
+		schemaField{"path", "vector[addr]"},
+		schemaField{"user_agent", "string"},
+		schemaField{"tls", "bool"},
+		schemaField{"fuids", "vector[string]"},
+	),
+	"ftp": withConnID(
+		schemaField{"user", "string"},
+		schemaField{"password", "string"},
+		schemaField{"command", "string"},
+		schemaField{"arg", "string"},
+		schemaField{"mime_type", "string"},
+		schemaField{"file_size", "count"},
+		schemaField{"reply_code", "count"},
+		schemaField{"reply_msg", "string"},
+		schemaField{"data_channel.passive", "bool"},
+		schemaField{"data_channel.orig_h", "addr"},
+		schemaField{"data_channel.resp_h", "addr"},
+		schemaField{"data_channel.resp_p", "port"},
+		schemaField{"fuid", "string"},
+	),
+	"weird": withConnID(
+		schemaField{"name", "string"},
+		schemaField{"addl", "string"},
+		schemaField{"notice", "bool"},
+		schemaField{"peer", "string"},
+	),
+	"notice": withConnID(
+		schemaField{"fuid", "string"},
+		schemaField{"file_mime_type", "string"},
+		schemaField{"file_desc", "string"},
+		schemaField{"proto", "enum"},
+		schemaField{"note", "enum"},
+		schemaField{"msg", "string"},
+		schemaField{"sub", "string"},
+		schemaField{"src", "addr"},
+		schemaField{"dst", "addr"},
+		schemaField{"p", "port"},
+		schemaField{"n", "count"},
+		schemaField{"peer_descr", "string"},
+		schemaField{"actions", "set[enum]"},
+		schemaField{"suppress_for", "interval"},
+		schemaField{"remote_location.country_code", "string"},
+		schemaField{"remote_location.region", "string"},
+		schemaField{"remote_location.city", "string"},
+		schemaField{"remote_location.latitude", "double"},
+		schemaField{"remote_location.longitude", "double"},
+	),
+	"x509": {
+		{"ts", "time"},
+		{"id", "string"},
+		{"certificate.version", "count"},
+		{"certificate.serial", "string"},
+		{"certificate.subject", "string"},
+		{"certificate.issuer", "string"},
+		{"certificate.not_valid_before", "time"},
+		{"certificate.not_valid_after", "time"},
+		{"certificate.key_alg", "string"},
+		{"certificate.sig_alg", "string"},
+		{"certificate.key_type", "string"},
+		{"certificate.key_length", "count"},
+		{"certificate.exponent", "string"},
+		{"certificate.curve", "string"},
+		{"san.dns", "vector[string]"},
+		{"san.uri", "vector[string]"},
+		{"san.email", "vector[string]"},
+		{"san.ip", "vector[addr]"},
+		{"basic_constraints.ca", "bool"},
+		{"basic_constraints.path_len", "count"},
+	},
+}
+
+// KnownSchema returns the default fields and types of a Bro log type,
+// as named by its #path header (ex: "conn", "dns", "http").
+// The boolean is false when the log type is not in the built-in table.
+func KnownSchema(path string) ([]string, []string, bool) {
+
+	schema, ok := knownSchemas[path]
+	if !ok {
+		return nil, nil, false
+	}
+
+	fields := make([]string, len(schema))
+	types := make([]string, len(schema))
+	for i, field := range schema {
+		fields[i] = field.name
+		types[i] = field.broType
+	}
+
+	return fields, types, true
+}
+
+// InferLogType returns the Bro log type (ex: "conn") whose KnownSchema
+// fields best match the #fields of the log, ex: for a log without a #path
+// header, along with a score from 0 to 1 of how well they match. A log
+// with exactly the fields of the type scores 1, and fields missing from
+// either lower the score. Types with equal scores are broken by name.
+func (p *Parser) InferLogType() (string, float64, error) {
+
+	fields, err := p.logFields()
+	if err != nil {
+		return "", 0, err
+	}
+
+	paths := make([]string, 0, len(knownSchemas))
+	for path := range knownSchemas {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var best string
+	var bestScore float64
+	for _, path := range paths {
+		known, _, _ := KnownSchema(path)
+		if score := fieldsMatch(fields, known); score > bestScore {
+			best, bestScore = path, score
+		}
+	}
+
+	if best == "" {
+		return "", 0, errors.New("No known log type matches the fields of the log")
+	}
+	return best, bestScore, nil
+}
+
+// fieldsMatch scores how well the fields of a log match the known fields
+// of a log type, as the Dice coefficient of both sets of fields.
+func fieldsMatch(fields, known []string) float64 {
+
+	inKnown := make(map[string]bool, len(known))
+	for _, field := range known {
+		inKnown[field] = true
+	}
+
+	var shared int
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if inKnown[field] && !seen[field] {
+			shared++
+		}
+		seen[field] = true
+	}
+
+	return 2 * float64(shared) / float64(len(seen)+len(inKnown))
+}
+
+// UnionFields merges the fields of two Bro logs, ex: from before and after
+// a Zeek upgrade added a field, into a common schema. The order of the
+// fields of both logs is kept: fields only in b follow the field they
+// follow in b. The fields of the union absent from a and from b are
+// returned too.
+func UnionFields(a, b []string) (union, missingA, missingB []string) {
+
+	union = append([]string{}, a...)
+	pos := -1
+	for _, field := range b {
+		if index, err := getIndex(union, field); err == nil {
+			pos = index
+			continue
+		}
+
+		pos++
+		union = append(union, "")
+		copy(union[pos+1:], union[pos:])
+		union[pos] = field
+		missingA = append(missingA, field)
+	}
+
+	for _, field := range a {
+		if _, err := getIndex(b, field); err != nil {
+			missingB = append(missingB, field)
+		}
+	}
+
+	return union, missingA, missingB
+}
+
+// ConformRow returns the values of a row with the given fields, in the
+// order of the union fields returned by UnionFields. Fields the row
+// doesn't have are filled with the unset marker of markers, ex: the ones
+// returned by the FieldMarkers of the parser of the row.
+func ConformRow(fields, union, row []string, markers FieldMarkers) []string {
+
+	unset := markers.orDefault().Unset
+
+	conformed := make([]string, len(union))
+	for i, field := range union {
+		index, err := getIndex(fields, field)
+		if err != nil || index >= len(row) {
+			conformed[i] = unset
+			continue
+		}
+		conformed[i] = row[index]
+	}
+
+	return conformed
+}
+
+// ReconcileSchemas finds the fields common to the Bro logs of many parsers,
+// ex: from sensors running different Zeek scripts, so that their rows can
+// be brought to a single schema. common keeps the order of the fields of the
+// first log. perParser holds the index of every common field in the
+// entries of each parser, in the order of parsers: for the entries of
+// parsers[n], entry[perParser[n][i]] is common[i]. The indexes aren't keyed
+// by path, as parsers of different file systems or sensors can share one
+// (ex: conn.log). Field names are compared after the transform of
+// SetFieldNameTransform.
+func ReconcileSchemas(parsers []*Parser) (common []string, perParser [][]int, err error) {
+
+	if len(parsers) == 0 {
+		return nil, nil, errors.New("No parsers to reconcile")
+	}
+
+	logFields := make([][]string, len(parsers))
+	for i, p := range parsers {
+		fields, err := p.logFields()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		normalized := make([]string, len(fields))
+		for j, field := range fields {
+			if p.fieldNameTransform != nil {
+				field = p.fieldNameTransform(field)
+			}
+			normalized[j] = field
+		}
+		logFields[i] = normalized
+	}
+
+	for _, field := range logFields[0] {
+		if field == "" {
+			continue
+		}
+		inAll := true
+		for _, fields := range logFields[1:] {
+			if _, err := getIndex(fields, field); err != nil {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			common = append(common, field)
+		}
+	}
+
+	perParser = make([][]int, len(parsers))
+	for i := range parsers {
+		indexes := make([]int, len(common))
+		for j, field := range common {
+			indexes[j], _ = getIndex(logFields[i], field)
+		}
+		perParser[i] = indexes
+	}
+
+	return common, perParser, nil
+}
+
+// SchemaDiff compares the #fields headers of two Bro logs, ex: from before
+// and after a Zeek config change, and returns the fields only in b, the
+// fields only in a, and the fields of both that moved: the fields that
+// aren't part of the longest sequence of fields of both in the same order.
+// Only the headers are read.
+func SchemaDiff(a, b *Parser) (added, removed, reordered []string, err error) {
+
+	fieldsA, _, err := a.Schema()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fieldsB, _, err := b.Schema()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var commonA, commonB []string
+	for _, field := range fieldsB {
+		if _, err := getIndex(fieldsA, field); err != nil {
+			added = append(added, field)
+		} else {
+			commonB = append(commonB, field)
+		}
+	}
+	for _, field := range fieldsA {
+		if _, err := getIndex(fieldsB, field); err != nil {
+			removed = append(removed, field)
+		} else {
+			commonA = append(commonA, field)
+		}
+	}
+
+	// Fields out of the longest run of fields kept in order were moved
+	inOrder := inOrderFields(commonA, commonB)
+	for _, field := range commonA {
+		if !inOrder[field] {
+			reordered = append(reordered, field)
+		}
+	}
+
+	return added, removed, reordered, nil
+}
+
+// inOrderFields returns the fields of the longest common subsequence of a
+// and b, which hold the same fields.
+func inOrderFields(a, b []string) map[string]bool {
+
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	inOrder := make(map[string]bool, lengths[0][0])
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			inOrder[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return inOrder
+}
+
+// SchemaTypeDiff compares the #types headers of two Bro logs, and returns
+// the fields of both whose type changed, in the order of a.
+func SchemaTypeDiff(a, b *Parser) ([]string, error) {
+
+	fieldsA, typesA, err := a.Schema()
+	if err != nil {
+		return nil, err
+	}
+	fieldsB, typesB, err := b.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if typesA == nil || typesB == nil {
+		return nil, errors.New("No types header found")
+	}
+
+	var retyped []string
+	for i, field := range fieldsA {
+		j, err := getIndex(fieldsB, field)
+		if err != nil || i >= len(typesA) || j >= len(typesB) {
+			continue
+		}
+		if typesA[i] != typesB[j] {
+			retyped = append(retyped, field)
+		}
+	}
+
+	return retyped, nil
+}
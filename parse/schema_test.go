@@ -0,0 +1,163 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	fields, types, ok := KnownSchema("conn")
+	assert.True(ok, "conn should be a known log type")
+	assert.Equal(len(fields), len(types), "fields and types should line up")
+	assert.Equal(fields[:7], []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto"}, "conn fields are incorrect")
+	assert.Equal(types[:7], []string{"time", "string", "addr", "port", "addr", "port", "enum"}, "conn types are incorrect")
+
+	// Modifying the returned slices must not change the built-in table
+	fields[0] = "changed"
+	fields, _, _ = KnownSchema("conn")
+	assert.Equal(fields[0], "ts", "built-in schema was modified")
+
+	for path := range knownSchemas {
+		fields, types, _ := KnownSchema(path)
+		assert.Equal(len(fields), len(types), "fields and types should line up for "+path)
+	}
+
+	_, _, ok = KnownSchema("not_a_log")
+	assert.False(ok, "unknown log types should not be found")
+}
+
+func TestInferLogType(t *testing.T) {
+	assert := assert.New(t)
+
+	connFields, _, _ := KnownSchema("conn")
+	header := func(fields []string) []byte {
+		return []byte("#separator \\x09\n#fields\t" + strings.Join(fields, "\t") + "\n")
+	}
+	fsys := fstest.MapFS{
+		"conn.log":    {Data: header(connFields)},
+		"partial.log": {Data: header([]string{"ts", "uid", "id.orig_h", "query", "qtype_name", "answers"})},
+		"custom.log":  {Data: header([]string{"event", "severity"})},
+	}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logType, score, err := parser.InferLogType()
+	assert.Nil(err)
+	assert.Equal(logType, "conn", "inferred the wrong log type")
+	assert.Equal(score, 1.0, "expected the fields of a type to match exactly")
+
+	parser, err = NewParserFS(fsys, "partial.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logType, score, err = parser.InferLogType()
+	assert.Nil(err)
+	assert.Equal(logType, "dns", "expected selected fields to match their type")
+	assert.True(score > 0 && score < 1, "expected missing fields to lower the score")
+
+	parser, err = NewParserFS(fsys, "custom.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = parser.InferLogType()
+	assert.EqualError(err, "No known log type matches the fields of the log")
+}
+
+func TestUnionFields(t *testing.T) {
+	assert := assert.New(t)
+
+	before := []string{"ts", "uid", "proto", "service"}
+	after := []string{"ts", "uid", "id.orig_h", "proto", "local_orig"}
+
+	union, missingBefore, missingAfter := UnionFields(before, after)
+	assert.Equal(union, []string{"ts", "uid", "id.orig_h", "proto", "local_orig", "service"}, "merged fields incorrectly")
+	assert.Equal(missingBefore, []string{"id.orig_h", "local_orig"}, "fields missing from the first log are incorrect")
+	assert.Equal(missingAfter, []string{"service"}, "fields missing from the second log are incorrect")
+
+	row := ConformRow(before, union, []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp", "http"}, DefaultFieldMarkers)
+	assert.Equal(row, []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "-", "tcp", "-", "http"}, "missing values should be unset")
+
+	markers := DefaultFieldMarkers
+	markers.Unset = "NULL"
+	row = ConformRow(before, union, []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp", "http"}, markers)
+	assert.Equal(row, []string{"1452684903.908400", "CbOiIv2wbbH7F25W21", "NULL", "tcp", "NULL", "http"}, "missing values should use the unset marker of the log")
+}
+
+func TestReconcileSchemas(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"sensor1/conn.log": {Data: []byte("#separator \\x09\n#fields\tts\tuid\tproto\tsensor_name\n")},
+		"sensor2/conn.log": {Data: []byte("#separator \\x09\n#fields\tts\tUID\tsite\tproto\n")},
+	}
+
+	var parsers []*Parser
+	for _, path := range []string{"sensor1/conn.log", "sensor2/conn.log"} {
+		parser, err := NewParserFS(fsys, path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser.SetFieldNameTransform(strings.ToLower)
+		parsers = append(parsers, parser)
+	}
+
+	common, perParser, err := ReconcileSchemas(parsers)
+	assert.Nil(err)
+	assert.Equal(common, []string{"ts", "uid", "proto"}, "expected the fields of every log")
+	assert.Equal(perParser, [][]int{{0, 1, 2}, {0, 1, 3}}, "indexes of the logs are incorrect")
+
+	// Logs with the same path in different filesystems each have their indexes
+	other := fstest.MapFS{"sensor1/conn.log": {Data: []byte("#separator \\x09\n#fields\tproto\tuid\tts\n")}}
+	parser, err := NewParserFS(other, "sensor1/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	common, perParser, err = ReconcileSchemas([]*Parser{parsers[0], parser})
+	assert.Nil(err)
+	assert.Equal(common, []string{"ts", "uid", "proto"}, "expected the fields of every log")
+	assert.Equal(perParser, [][]int{{0, 1, 2}, {2, 1, 0}}, "indexes of logs with the same path are incorrect")
+
+	_, _, err = ReconcileSchemas(nil)
+	assert.NotNil(err, "expected an error without parsers")
+}
+
+func TestSchemaDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"before/conn.log": {Data: []byte("#separator \\x09\n#fields\tts\tuid\tproto\tservice\tduration\n#types\ttime\tstring\tenum\tstring\tinterval\n")},
+		"after/conn.log":  {Data: []byte("#separator \\x09\n#fields\tts\tuid\tservice\tduration\tproto\tsensor_name\n#types\ttime\tstring\tstring\tinterval\tstring\tstring\n")},
+	}
+
+	before, err := NewParserFS(fsys, "before/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := NewParserFS(fsys, "after/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed, reordered, err := SchemaDiff(before, after)
+	assert.Nil(err)
+	assert.Equal(added, []string{"sensor_name"}, "expected the field added")
+	assert.Nil(removed, "expected no field removed")
+	assert.Equal(reordered, []string{"proto"}, "expected the field moved")
+
+	added, removed, _, err = SchemaDiff(after, before)
+	assert.Nil(err)
+	assert.Nil(added, "expected no field added")
+	assert.Equal(removed, []string{"sensor_name"}, "expected the field removed")
+
+	retyped, err := SchemaTypeDiff(before, after)
+	assert.Nil(err)
+	assert.Equal(retyped, []string{"proto"}, "expected the field whose type changed")
+}
@@ -0,0 +1,63 @@
+package parse
+
+// Segment is a part of a Bro log holding the entries of a single log type,
+// see Segments.
+type Segment struct {
+	Path   string
+	Line   int
+	Fields []string
+	Types  []string
+	Rows   [][]string
+}
+
+// Segments splits a Bro log that is a concatenation of logs of different
+// types, ex: a conn.log and a dns.log merged by mistake, into a segment per
+// log. A segment ends at every header block with a different #path, or with
+// different #fields, and holds the fields and types of its header along
+// with its entries, all of their values included. Line is the line of the
+// first entry of the segment. Header blocks repeated by rotation (ex: logs
+// merged with cat) don't end a segment.
+func (p *Parser) Segments() ([]Segment, error) {
+
+	var segments []Segment
+	var current *Segment
+	var path string
+	var types []string
+
+	// Header lines are seen through the header callback, which still gets
+	// called if one is set
+	callback := p.headerCallback
+	defer func() { p.headerCallback = callback }()
+
+	p.headerCallback = func(key, value string) {
+		if callback != nil {
+			callback(key, value)
+		}
+		switch key {
+		case "path":
+			path = value
+		case "types":
+			types = splitValues(value, p.separator, p.collapseSeparators)
+		}
+	}
+
+	err := p.eachEntry(func(lineNum int, entry []string) error {
+		if current == nil || current.Path != path || !equalFields(current.Fields, p.blockFields) {
+			segments = append(segments, Segment{
+				Path:   path,
+				Line:   lineNum,
+				Fields: p.blockFields,
+				Types:  types,
+			})
+			current = &segments[len(segments)-1]
+		}
+
+		current.Rows = append(current.Rows, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := "#separator \\x09\n#path\tconn\n#fields\tts\tuid\tproto\n#types\ttime\tstring\tenum\n"
+	dns := "#separator \\x09\n#path\tdns\n#fields\tts\tquery\n#types\ttime\tstring\n"
+
+	fsys := fstest.MapFS{"merged.log": {Data: []byte(conn +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n" +
+		"#close\t2016-01-13-07-00-00\n" +
+		conn +
+		"1452684904.908400\tC7fIlMZDuRiqjpYbb\tudp\n" +
+		dns +
+		"1452684905.908400\texample.com\n")}}
+
+	parser, err := NewParserFS(fsys, "merged.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	parser.SetHeaderCallback(func(key, value string) { keys = append(keys, key) })
+
+	segments, err := parser.Segments()
+	assert.Nil(err)
+	assert.Equal(segments, []Segment{
+		{
+			Path:   "conn",
+			Line:   5,
+			Fields: []string{"ts", "uid", "proto"},
+			Types:  []string{"time", "string", "enum"},
+			Rows: [][]string{
+				{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp"},
+				{"1452684904.908400", "C7fIlMZDuRiqjpYbb", "udp"},
+			},
+		},
+		{
+			Path:   "dns",
+			Line:   16,
+			Fields: []string{"ts", "query"},
+			Types:  []string{"time", "string"},
+			Rows:   [][]string{{"1452684905.908400", "example.com"}},
+		},
+	}, "split the log into segments incorrectly")
+	assert.Equal(len(keys), 13, "the header callback should still be called")
+}
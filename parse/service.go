@@ -0,0 +1,126 @@
+package parse
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultServiceNames maps well known ports to the name of their service.
+var defaultServiceNames = map[uint16]string{
+	20:   "ftp-data",
+	21:   "ftp",
+	22:   "ssh",
+	23:   "telnet",
+	25:   "smtp",
+	53:   "dns",
+	67:   "dhcp",
+	68:   "dhcp",
+	69:   "tftp",
+	80:   "http",
+	88:   "kerberos",
+	110:  "pop3",
+	123:  "ntp",
+	137:  "netbios-ns",
+	139:  "netbios-ssn",
+	143:  "imap",
+	161:  "snmp",
+	389:  "ldap",
+	443:  "https",
+	445:  "smb",
+	465:  "smtps",
+	514:  "syslog",
+	587:  "submission",
+	636:  "ldaps",
+	993:  "imaps",
+	995:  "pop3s",
+	1433: "mssql",
+	1883: "mqtt",
+	3306: "mysql",
+	3389: "rdp",
+	5060: "sip",
+	5432: "postgresql",
+	5900: "vnc",
+	6379: "redis",
+	8080: "http-alt",
+	8443: "https-alt",
+}
+
+// serviceNames holds the table of ServiceNameParse. It is replaced by a
+// copy on every change, so that it can be read while it is changed.
+var serviceNames atomic.Pointer[map[uint16]string]
+
+func init() {
+	serviceNames.Store(&defaultServiceNames)
+}
+
+// serviceNamesMu serializes the changes of SetServiceNames.
+var serviceNamesMu sync.Mutex
+
+// SetServiceNames adds or overrides the service names of ports used by
+// ServiceNameParse, including by the functions it already returned. Ports
+// given an empty name are removed from the table.
+func SetServiceNames(names map[uint16]string) {
+	serviceNamesMu.Lock()
+	defer serviceNamesMu.Unlock()
+
+	current := *serviceNames.Load()
+	updated := make(map[uint16]string, len(current)+len(names))
+	for port, name := range current {
+		updated[port] = name
+	}
+	for port, name := range names {
+		if name == "" {
+			delete(updated, port)
+			continue
+		}
+		updated[port] = name
+	}
+	serviceNames.Store(&updated)
+}
+
+// ServiceNameParse returns a Parse() function that appends a column to the
+// row for each of portFields (ex: "id.resp_p"), holding the service name
+// of its port, from a table of well known ports that SetServiceNames can
+// change. Ports may have their protocol appended, ex: "80/tcp". Unknown
+// ports and unset values get the unset value "-".
+func ServiceNameParse(portFields []string) Parse {
+	return func(fields, row []string) ([]string, error) {
+
+		names := *serviceNames.Load()
+		modifiedRow := append([]string{}, row...)
+		for _, portField := range portFields {
+			index, err := getIndex(fields, portField)
+			if err != nil {
+				return nil, err
+			}
+			if index >= len(row) {
+				return nil, errors.New("Row is missing field: " + portField)
+			}
+			modifiedRow = append(modifiedRow, serviceName(names, row[index]))
+		}
+
+		return modifiedRow, nil
+	}
+}
+
+// serviceName returns the service name of a port value in names.
+func serviceName(names map[uint16]string, value string) string {
+
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		value = value[:i]
+	}
+
+	port, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return DefaultFieldMarkers.Unset
+	}
+
+	name, ok := names[uint16(port)]
+	if !ok {
+		return DefaultFieldMarkers.Unset
+	}
+	return name
+}
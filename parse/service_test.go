@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceNameParse(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []string{"id.orig_p", "id.resp_p"}
+	parseFunc := ServiceNameParse([]string{"id.resp_p", "id.orig_p"})
+
+	row, err := parseFunc(fields, []string{"37218", "443"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"37218", "443", "https", "-"}, "expected a service column per port field")
+
+	row, err = parseFunc(fields, []string{"-", "80/tcp"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"-", "80/tcp", "http", "-"}, "expected ports with protocols to be named")
+
+	SetServiceNames(map[uint16]string{37218: "custom", 443: "tls"})
+	defer SetServiceNames(map[uint16]string{37218: "", 443: "https"})
+
+	row, err = parseFunc(fields, []string{"37218", "443"})
+	assert.Nil(err)
+	assert.Equal(row[2:], []string{"tls", "custom"}, "expected names added to and overridden in the table")
+
+	_, err = ServiceNameParse([]string{"missing"})(fields, []string{"37218", "443"})
+	assert.NotNil(err, "expected an error for an unknown field")
+}
+
+func TestSetServiceNamesConcurrently(t *testing.T) {
+
+	parseFunc := ServiceNameParse([]string{"id.resp_p"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			parseFunc([]string{"id.resp_p"}, []string{"8000"})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		SetServiceNames(map[uint16]string{8000: "http-dev"})
+	}
+	<-done
+	SetServiceNames(map[uint16]string{8000: ""})
+}
@@ -0,0 +1,123 @@
+package parse
+
+import (
+	"container/list"
+	"errors"
+	"time"
+)
+
+// Session is the consecutive rows of a host, ex: the connections of
+// id.orig_h in a conn.log, with no more than the idle timeout between
+// them, see Sessionize. Start and End are the ts of its first and last
+// rows.
+type Session struct {
+	Host  string
+	Start time.Time
+	End   time.Time
+	Rows  [][]string
+}
+
+// Sessionize groups the rows of the Bro log into the sessions of the host
+// in hostField, and returns a channel of the sessions as they end. A
+// session ends once the log reaches a ts more than idle past its last row,
+// and the sessions still open at the end of the log end with it. Hosts of
+// type addr are canonicalized, see CanonicalizeAddr. Rows are
+// the same as the ones BufferRow pushes, and should be in ts order, as
+// with any Bro log. Rows without a host or a valid ts are skipped. As with
+// BufferRow, errors are pushed into p.Errors, and both channels are closed
+// once parsing is done.
+func (p *Parser) Sessionize(hostField string, idle time.Duration) <-chan Session {
+
+	sessions := make(chan Session)
+	errs := p.Errors
+
+	go func() {
+		if err := p.sessionize(hostField, idle, sessions); err != nil {
+			p.reportError(err)
+		}
+
+		// Errors are closed first, so that they are all pushed once the
+		// sessions are drained
+		if errs != nil {
+			close(errs)
+		}
+		close(sessions)
+	}()
+
+	return sessions
+}
+
+func (p *Parser) sessionize(hostField string, idle time.Duration, sessions chan<- Session) error {
+
+	if p.fields == nil {
+		return errors.New("No fields parsed")
+	}
+
+	// Fields are indexed in their emitted order
+	p.applyFieldOrder()
+	hostIndex, err := getIndex(p.fields, hostField)
+	if err != nil {
+		return err
+	}
+	tsIndex, err := getIndex(p.fields, "ts")
+	if err != nil {
+		return err
+	}
+
+	// Open sessions are kept in the order they were last seen, so that the
+	// ones idle for longest are first
+	open := list.New()
+	// Hosts are grouped however their addrs are written
+	isAddr := p.isAddrField(hostIndex)
+	byHost := make(map[string]*list.Element)
+	var overMaxKeys bool
+	end := func(element *list.Element) {
+		session := open.Remove(element).(*Session)
+		delete(byHost, session.Host)
+		sessions <- *session
+	}
+
+	err = p.eachSelectedRow(func(lineNum int, row []string) error {
+		host := groupKey(isAddr, row[hostIndex])
+		if host == p.markers.Unset {
+			return nil
+		}
+		ts, err := ParseTime(row[tsIndex])
+		if err != nil {
+			return nil
+		}
+
+		for open.Len() > 0 && ts.Sub(open.Front().Value.(*Session).End) > idle {
+			end(open.Front())
+		}
+
+		row = p.output(row, row)
+		if element, ok := byHost[host]; ok {
+			session := element.Value.(*Session)
+			session.Rows = append(session.Rows, row)
+			if ts.After(session.End) {
+				session.End = ts
+			}
+			open.MoveToBack(element)
+			return nil
+		}
+
+		byHost[host] = open.PushBack(&Session{Host: host, Start: ts, End: ts, Rows: [][]string{row}})
+
+		// Past the bound, the session idle for longest ends early
+		if p.exceedsMaxKeys(len(byHost)) {
+			end(open.Front())
+			if !overMaxKeys {
+				overMaxKeys = true
+				p.reportError(&MaxKeysError{Operation: "Sessionize by " + hostField, MaxKeys: p.maxKeys})
+			}
+		}
+		return nil
+	})
+
+	for open.Len() > 0 {
+		end(open.Front())
+	}
+
+	return err
+}
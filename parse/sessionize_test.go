@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionize(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\tproto\n" +
+		"#types\ttime\taddr\tenum\n" +
+		"1452684900.000000\t10.1.20.227\ttcp\n" +
+		"1452684910.000000\t10.1.20.228\tudp\n" +
+		"1452684920.000000\t10.1.20.227\ttcp\n" +
+		"1452684925.000000\t-\ttcp\n" +
+		"1452684990.000000\t10.1.20.227\ticmp\n" +
+		"1452684995.000000\t10.1.20.228\tudp\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.CreateErrorBuffer(10)
+
+	var sessions []Session
+	for session := range parser.Sessionize("id.orig_h", 30*time.Second) {
+		sessions = append(sessions, session)
+	}
+
+	if assert.Equal(len(sessions), 4, "expected a new session after an idle host") {
+		assert.Equal(sessions[0].Host, "10.1.20.228", "expected the session idle for longest to end first")
+		assert.Equal(len(sessions[0].Rows), 1)
+
+		assert.Equal(sessions[1].Host, "10.1.20.227")
+		assert.Equal(len(sessions[1].Rows), 2, "expected rows within the timeout in one session")
+		assert.Equal(sessions[1].Start, time.Unix(1452684900, 0).UTC())
+		assert.Equal(sessions[1].End, time.Unix(1452684920, 0).UTC())
+
+		assert.Equal(sessions[2].Rows, [][]string{{"1452684990.000000", "10.1.20.227", "icmp"}}, "expected open sessions to end with the log")
+		assert.Equal(sessions[3].Host, "10.1.20.228")
+	}
+	var errs []error
+	for err := range parser.Errors {
+		errs = append(errs, err)
+	}
+	assert.Equal(len(errs), 0, "expected no errors")
+
+	parser.CreateErrorBuffer(10)
+	for range parser.Sessionize("host", time.Second) {
+	}
+	err = <-parser.Errors
+	assert.EqualError(err, "Couldn't match field to parse with one in bro log, field is: host")
+}
@@ -0,0 +1,35 @@
+package parse
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pkg/sftp"
+)
+
+// NewParserSFTP returns a new parser that streams a Bro log from a remote
+// host over client without copying it first. Logs with a .gz or .bz2 path
+// are decompressed. Every pass over the log opens the remote file again, and
+// errors of the connection are returned with the path they happened on.
+func NewParserSFTP(client *sftp.Client, path string, allFields bool) (*Parser, error) {
+
+	if client == nil {
+		return nil, errors.New("SFTP client is nil")
+	}
+
+	return NewParserReader(path, func(offset int64) (io.ReadCloser, error) {
+		file, err := client.Open(path)
+		if err != nil {
+			return nil, errors.New("Couldn't open " + path + " over SFTP: " + err.Error())
+		}
+
+		if offset > 0 {
+			if _, err := file.Seek(offset, io.SeekStart); err != nil {
+				file.Close()
+				return nil, errors.New("Couldn't seek " + path + " over SFTP: " + err.Error())
+			}
+		}
+
+		return file, nil
+	}, allFields)
+}
@@ -0,0 +1,78 @@
+package parse
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeSFTP returns a client of an in-memory SFTP server.
+func pipeSFTP(t *testing.T) *sftp.Client {
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter}, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client waits for the server to end the connection once closed
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return client
+}
+
+func TestNewParserSFTP(t *testing.T) {
+	assert := assert.New(t)
+
+	client := pipeSFTP(t)
+	file, err := client.Create("/conn.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte(followHeader + "1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n")); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	parser, err := NewParserSFTP(client, "/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{{"1452684903.908400", "CbOiIv2wbbH7F25W21", "tcp"}}, "read the remote log incorrectly")
+
+	parser.SetByteRange(int64(len(followHeader)+1), 0)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 0, "expected the remote log to be read from the offset")
+
+	parser, err = NewParserSFTP(client, "/dns.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.ParseAllFields()
+	if assert.NotNil(err, "expected an error for a missing remote log") {
+		assert.Contains(err.Error(), "Couldn't open /dns.log over SFTP: ", "expected the path in connection errors")
+	}
+
+	_, err = NewParserSFTP(nil, "/conn.log", true)
+	assert.NotNil(err, "expected an error for a nil client")
+}
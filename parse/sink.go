@@ -0,0 +1,21 @@
+package parse
+
+// Sink receives parsed rows, ex: to forward them to a file or a remote service.
+type Sink interface {
+	Write(row []string) error
+	Close() error
+}
+
+// Drain reads rows from a channel, such as p.Row, and writes them to a sink
+// until the channel is closed. The sink is closed once the channel is drained.
+func Drain(rows chan []string, sink Sink) error {
+
+	for row := range rows {
+		if err := sink.Write(row); err != nil {
+			sink.Close()
+			return err
+		}
+	}
+
+	return sink.Close()
+}
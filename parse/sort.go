@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// SortBy loads every entry of the Bro log into memory and returns them
+// sorted by field, in ascending order. Entries contain all the values of
+// the log, so they can be written back out with WriteLog. When numeric is
+// true values are compared as numbers, and values that aren't numbers
+// (ex: the unset field "-" or NaN) sort first. Entries with equal values keep
+// their original order. When SetMaxKeys is set, sorting ends with a
+// *MaxKeysError once more entries than the bound are loaded.
+func (p *Parser) SortBy(field string, numeric bool) ([][]string, error) {
+
+	allFields, err := p.logFields()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := getIndex(allFields, field)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries [][]string
+	var keys []float64
+
+	err = p.eachEntry(func(lineNum int, entry []string) error {
+		if index >= len(entry) {
+			return nil
+		}
+		entries = append(entries, entry)
+		if numeric {
+			keys = append(keys, numericKey(entry[index]))
+		}
+		if p.exceedsMaxKeys(len(entries)) {
+			return &MaxKeysError{Operation: "SortBy " + field, MaxKeys: p.maxKeys, Stopped: true}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if numeric {
+		sort.Stable(byNumericKey{entries, keys})
+	} else {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i][index] < entries[j][index]
+		})
+	}
+
+	return entries, nil
+}
+
+// numericKey parses a value for numeric sorting. Values that aren't
+// numbers, NaN included since it can't be compared, sort first.
+func numericKey(value string) float64 {
+	key, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(key) {
+		return math.Inf(-1)
+	}
+	return key
+}
+
+// byNumericKey sorts entries by their pre-parsed numeric keys.
+type byNumericKey struct {
+	entries [][]string
+	keys    []float64
+}
+
+func (b byNumericKey) Len() int           { return len(b.entries) }
+func (b byNumericKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byNumericKey) Swap(i, j int) {
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortBy(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\torig_bytes\n" +
+		"#types\tstring\tcount\n" +
+		"C1\t900\n" +
+		"C2\t-\n" +
+		"C3\t1000\n" +
+		"C4\t900\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parser.SortBy("orig_bytes", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var uids []string
+	for _, entry := range entries {
+		uids = append(uids, entry[0])
+	}
+	assert.Equal(uids, []string{"C2", "C1", "C4", "C3"}, "sorted numerically incorrectly")
+
+	entries, err = parser.SortBy("orig_bytes", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(entries[0][1], "-", "sorted as strings incorrectly")
+	assert.Equal(entries[1][1], "1000", "sorted as strings incorrectly")
+
+	var out bytes.Buffer
+	err = parser.WriteLog(&out, entries)
+	assert.Nil(err)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(len(lines), 8, "expected the header, four entries and #close")
+	assert.Equal(lines[2], "#types\tstring\tcount", "header should be kept")
+	assert.Equal(lines[3], "C2\t-", "entries should be written in sorted order")
+	assert.True(strings.HasPrefix(lines[7], "#close\t"), "log should end with #close")
+
+	// NaN sorts first along with the values that aren't numbers
+	fsys["nan.log"] = &fstest.MapFile{Data: []byte("#separator \\x09\n#fields\tuid\tduration\n#types\tstring\tinterval\n" +
+		"C1\t2.0\nC2\tNaN\nC3\t1.0\nC4\t-\nC5\t3.0\nC6\tnan\nC7\t0.5\n")}
+	nan, err := NewParserFS(fsys, "nan.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = nan.SortBy("duration", true)
+	assert.Nil(err)
+	uids = nil
+	for _, entry := range entries {
+		uids = append(uids, entry[0])
+	}
+	assert.Equal(uids, []string{"C2", "C4", "C6", "C7", "C3", "C1", "C5"}, "expected NaN to sort first")
+
+	_, err = parser.SortBy("not_a_field", false)
+	assert.NotNil(err, "expected an error for an unknown field")
+}
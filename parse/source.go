@@ -0,0 +1,39 @@
+package parse
+
+// The names of the fields SetSourceFields appends.
+const (
+	SourceFileField = "_source_file"
+	LogPathField    = "_log_path"
+)
+
+// SetSourceFields enables or disables appending the file a row was read
+// from and the type of its log, from the #path header of its header block
+// (ex: "conn"), to every row pushed by BufferRow or returned by ReadAll,
+// and as the SourceFileField and LogPathField of every TypedMap. This keeps
+// track of where rows came from when the logs of a whole directory are
+// merged into one stream. The log type is unset when there is no #path
+// header. Source fields follow the computed fields.
+func (p *Parser) SetSourceFields(enabled bool) {
+	p.sourceFields = enabled
+}
+
+// sourceValues returns the values of the source fields of the current row.
+func (p *Parser) sourceValues() []string {
+	logPath := p.blockPath
+	if logPath == "" {
+		logPath = p.markers.Unset
+	}
+	return []string{p.filepath, logPath}
+}
+
+// withSourceFields returns a typed row with its source fields appended.
+func (p *Parser) withSourceFields(typedMap TypedMap) TypedMap {
+	var logPath interface{}
+	if p.blockPath != "" {
+		logPath = p.blockPath
+	}
+	return append(typedMap,
+		FieldValue{Field: SourceFileField, Value: p.filepath},
+		FieldValue{Field: LogPathField, Value: logPath},
+	)
+}
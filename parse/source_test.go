@@ -0,0 +1,53 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSourceFields(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"spool/conn.log": {Data: []byte("#separator \\x09\n" +
+		"#path\tconn\n" +
+		"#fields\tid.orig_h\tproto\n" +
+		"#types\taddr\tenum\n" +
+		"10.1.20.227\ttcp\n" +
+		"#path\tconn_summary\n" +
+		"10.1.20.228\tudp\n")}}
+
+	parser, err := NewParserFS(fsys, "spool/conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	parser.SetSourceFields(true)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"10.1.20.227", "tcp", "spool/conn.log", "conn"},
+		{"10.1.20.228", "udp", "spool/conn.log", "conn_summary"},
+	}, "expected the source file and log type of every row")
+
+	parser.CreateTypedMapBuffer(2)
+	go parser.BufferTypedRowMap()
+	typedMap := <-parser.TypedRowMap
+	for range parser.TypedRowMap {
+	}
+	source, _ := typedMap.Get(SourceFileField)
+	logPath, _ := typedMap.Get(LogPathField)
+	assert.Equal(source, "spool/conn.log", "expected the source file in typed rows")
+	assert.Equal(logPath, "conn", "expected the log type in typed rows")
+
+	parser.SetSourceFields(false)
+	rows, err = parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows[0]), 2, "expected no source fields when disabled")
+}
@@ -0,0 +1,142 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSummary reports on a parse as a whole, see SetSummary: the rows
+// emitted, the malformed rows skipped, the bytes read from the Bro log,
+// the ts of the first and last rows, the number of distinct values of the
+// fields summarized, and how long the parse took.
+type ParseSummary struct {
+	Rows          int
+	MalformedRows int
+	BytesRead     int64
+	First         time.Time
+	Last          time.Time
+	Distinct      map[string]int
+	Elapsed       time.Duration
+
+	fields []string
+}
+
+// SetSummary enables or disables summarizing every parse, ex: for a CLI
+// to report on a log once BufferRow is done, see Summary. The distinct
+// values of distinctFields are counted, which holds them all in memory,
+// and addr values are counted once however they are written.
+func (p *Parser) SetSummary(enabled bool, distinctFields []string) {
+	p.summarizing = enabled
+	p.summaryFields = distinctFields
+	if !enabled {
+		p.summary = nil
+	}
+}
+
+// Summary returns the summary of the last parse, or nil when summaries
+// are disabled.
+func (p *Parser) Summary() *ParseSummary {
+	return p.summary
+}
+
+// String returns the summary as a human readable report, a line per count.
+func (s *ParseSummary) String() string {
+
+	var report strings.Builder
+	report.WriteString("Rows: " + strconv.Itoa(s.Rows) + "\n")
+	report.WriteString("Malformed rows: " + strconv.Itoa(s.MalformedRows) + "\n")
+	report.WriteString("Bytes read: " + strconv.FormatInt(s.BytesRead, 10) + "\n")
+	if !s.First.IsZero() {
+		report.WriteString("Time span: " + s.First.Format(time.RFC3339Nano) + " to " +
+			s.Last.Format(time.RFC3339Nano) + " (" + s.Last.Sub(s.First).String() + ")\n")
+	}
+	for _, field := range s.fields {
+		report.WriteString("Distinct " + field + ": " + strconv.Itoa(s.Distinct[field]) + "\n")
+	}
+	report.WriteString("Elapsed: " + s.Elapsed.String() + "\n")
+	return report.String()
+}
+
+// summarizer collects the summary of a parse, with its own metrics
+// updating the metrics of the parser too.
+type summarizer struct {
+	summary *ParseSummary
+	metrics *parseMetrics
+	start   time.Time
+	tsIndex int
+	indexes []int
+	isAddr  []bool
+	seen    []map[string]struct{}
+}
+
+// startSummary starts summarizing a parse, if enabled.
+func (p *Parser) startSummary() (*summarizer, error) {
+
+	if !p.summarizing || p.fields == nil {
+		return nil, nil
+	}
+
+	s := &summarizer{
+		summary: &ParseSummary{Distinct: make(map[string]int), fields: p.summaryFields},
+		metrics: &parseMetrics{parent: p.metrics},
+		start:   time.Now(),
+		tsIndex: -1,
+	}
+	if index, err := getIndex(p.fields, "ts"); err == nil {
+		s.tsIndex = index
+	}
+	for _, field := range p.summaryFields {
+		index, err := getIndex(p.fields, field)
+		if err != nil {
+			return nil, err
+		}
+		s.indexes = append(s.indexes, index)
+		s.isAddr = append(s.isAddr, p.isAddrField(index))
+		s.seen = append(s.seen, make(map[string]struct{}))
+	}
+
+	p.metrics = s.metrics
+	return s, nil
+}
+
+// observe adds an emitted row to the summary.
+func (s *summarizer) observe(row []string) {
+	if s == nil {
+		return
+	}
+
+	if s.tsIndex >= 0 {
+		if ts, err := ParseTime(row[s.tsIndex]); err == nil {
+			if s.summary.First.IsZero() || ts.Before(s.summary.First) {
+				s.summary.First = ts
+			}
+			if ts.After(s.summary.Last) {
+				s.summary.Last = ts
+			}
+		}
+	}
+
+	for i, index := range s.indexes {
+		s.seen[i][groupKey(s.isAddr[i], row[index])] = struct{}{}
+	}
+}
+
+// finish ends the summary, making it the summary of the parser.
+func (s *summarizer) finish(p *Parser) {
+	if s == nil {
+		return
+	}
+
+	p.metrics = s.metrics.parent
+
+	summary := s.summary
+	summary.Rows = int(s.metrics.rowsParsed.Load())
+	summary.MalformedRows = int(s.metrics.malformedRows.Load())
+	summary.BytesRead = int64(s.metrics.bytesRead.Load())
+	for i, field := range p.summaryFields {
+		summary.Distinct[field] = len(s.seen[i])
+	}
+	summary.Elapsed = time.Since(s.start)
+	p.summary = summary
+}
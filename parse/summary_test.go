@@ -0,0 +1,68 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	log := "#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\tproto\n" +
+		"#types\ttime\taddr\tenum\n" +
+		"1452684903.000000\t10.1.20.227\ttcp\n" +
+		"1452684904.000000\t::ffff:10.1.20.227\tudp\n" +
+		"x\n" +
+		"1452684963.000000\t10.1.20.228\ttcp\n"
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(log)}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	assert.Nil(parser.Summary(), "expected no summary by default")
+
+	assert.Nil(parser.SetMetrics(prometheus.NewRegistry()))
+	metrics := parser.metrics
+	parser.SetSummary(true, []string{"id.orig_h", "proto"})
+	parser.CreateBuffer(10)
+
+	go parser.BufferRow()
+	for range parser.Row {
+	}
+
+	summary := parser.Summary()
+	if summary == nil {
+		t.Fatal("expected a summary once parsed")
+	}
+	assert.Equal(summary.Rows, 3, "counted rows incorrectly")
+	assert.Equal(summary.MalformedRows, 1, "counted malformed rows incorrectly")
+	assert.Equal(summary.BytesRead, int64(len(log)), "counted bytes read incorrectly")
+	assert.Equal(summary.First, time.Unix(1452684903, 0).UTC())
+	assert.Equal(summary.Last, time.Unix(1452684963, 0).UTC())
+	assert.Equal(summary.Distinct, map[string]int{"id.orig_h": 2, "proto": 2}, "counted distinct values incorrectly")
+	assert.True(strings.HasPrefix(summary.String(), "Rows: 3\nMalformed rows: 1\n"), "expected a line per count")
+	assert.Contains(summary.String(), "Distinct id.orig_h: 2\nDistinct proto: 2\n")
+
+	// The metrics of the parser are still updated
+	assert.Equal(metrics.rowsParsed.Load(), uint64(3), "expected the metrics to be updated too")
+
+	// Summaries are of the last parse only
+	parser.CreateBuffer(10)
+	go parser.BufferRow()
+	for range parser.Row {
+	}
+	assert.Equal(parser.Summary().Rows, 3, "expected the summary of the last parse")
+	assert.Equal(metrics.rowsParsed.Load(), uint64(6))
+}
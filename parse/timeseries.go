@@ -0,0 +1,162 @@
+package parse
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TimeSeries sums the numeric valueField of the entries of the Bro log into
+// buckets of window by their ts, ex: the bytes sent per minute, returning
+// the start of every bucket with its sum in ascending order of time. The
+// slices can be handed to a plotting library as they are, ex: as the X and
+// Y of gonum/plot's plotter.XYs. Buckets without entries between the first
+// and the last are included with a sum of 0, and entries with an unset ts
+// or value are skipped. When SetMaxKeys is set and exceeded by the number
+// of buckets, counting those filled in, the window is doubled until it
+// isn't, and the buckets are returned with the larger window. Without it,
+// an error is returned for more than 1000000 buckets, ex: for an outlier ts
+// far from the others.
+func (p *Parser) TimeSeries(valueField string, window time.Duration) (times []time.Time, values []float64, err error) {
+
+	if window <= 0 {
+		return nil, nil, errors.New("Window must be positive")
+	}
+
+	fields, types, err := p.Schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tsIndex, err := getIndex(fields, "ts")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valueIndex, err := getIndex(fields, valueField)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var valueType string
+	if valueIndex < len(types) {
+		valueType = types[valueIndex]
+	}
+
+	sums := make(map[time.Time]float64)
+	var overMaxKeys bool
+	err = p.eachEntry(func(lineNum int, entry []string) error {
+		if tsIndex >= len(entry) || valueIndex >= len(entry) {
+			return nil
+		}
+
+		ts, err := ParseTime(entry[tsIndex])
+		if err != nil {
+			return nil
+		}
+
+		value, ok, err := p.seriesValue(valueField, valueType, entry[valueIndex])
+		if err != nil {
+			return errors.New("Line " + strconv.Itoa(lineNum) + ": " + err.Error())
+		}
+		if ok {
+			sums[ts.Truncate(window)] += value
+		}
+
+		if p.exceedsMaxKeys(len(sums)) {
+			if !overMaxKeys {
+				overMaxKeys = true
+				p.reportError(&MaxKeysError{Operation: "TimeSeries of " + valueField, MaxKeys: p.maxKeys})
+			}
+			for p.exceedsMaxKeys(len(sums)) {
+				window *= 2
+				sums = rebucket(sums, window)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(sums) == 0 {
+		return nil, nil, nil
+	}
+
+	// The buckets filled in between the first and the last count too, so
+	// that a single outlier ts can't have millions of them held
+	buckets := sortedBuckets(sums)
+	for p.exceedsMaxKeys(bucketSpan(buckets, window)) {
+		if !overMaxKeys {
+			overMaxKeys = true
+			p.reportError(&MaxKeysError{Operation: "TimeSeries of " + valueField, MaxKeys: p.maxKeys})
+		}
+		window *= 2
+		sums = rebucket(sums, window)
+		buckets = sortedBuckets(sums)
+	}
+	if span := bucketSpan(buckets, window); span > maxTimeSeriesBuckets {
+		return nil, nil, errors.New("TimeSeries of " + valueField + " spans " + strconv.Itoa(span) + " buckets of " + window.String() + ", more than " + strconv.Itoa(maxTimeSeriesBuckets) + ": use a larger window or SetMaxKeys")
+	}
+
+	for bucket := buckets[0]; !bucket.After(buckets[len(buckets)-1]); bucket = bucket.Add(window) {
+		times = append(times, bucket)
+		values = append(values, sums[bucket])
+	}
+
+	return times, values, nil
+}
+
+// maxTimeSeriesBuckets is the most buckets TimeSeries returns when
+// SetMaxKeys isn't set.
+const maxTimeSeriesBuckets = 1000000
+
+// sortedBuckets returns the buckets of sums in ascending order of time.
+func sortedBuckets(sums map[time.Time]float64) []time.Time {
+	buckets := make([]time.Time, 0, len(sums))
+	for bucket := range sums {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Before(buckets[j]) })
+	return buckets
+}
+
+// bucketSpan returns the number of buckets of window from the first of the
+// sorted buckets to the last, both included.
+func bucketSpan(buckets []time.Time, window time.Duration) int {
+	return int(buckets[len(buckets)-1].Sub(buckets[0])/window) + 1
+}
+
+// rebucket returns the sums of buckets merged into the buckets of a larger
+// window, a multiple of theirs.
+func rebucket(sums map[time.Time]float64, window time.Duration) map[time.Time]float64 {
+	merged := make(map[time.Time]float64, len(sums)/2+1)
+	for bucket, sum := range sums {
+		merged[bucket.Truncate(window)] += sum
+	}
+	return merged
+}
+
+// seriesValue converts the value of a field to a number by its type, or
+// as a plain number for logs without a #types header. The boolean is false
+// for unset values.
+func (p *Parser) seriesValue(field, broType, value string) (float64, bool, error) {
+
+	if broType == "" {
+		if value == p.markers.Unset {
+			return 0, false, nil
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false, errors.New("Field is not a number: " + field)
+		}
+		return number, true, nil
+	}
+
+	typed, err := p.coerce(broType, value)
+	if err != nil {
+		return 0, false, err
+	}
+	return numericValue(field, typed)
+}
@@ -0,0 +1,81 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSeries(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tuid\torig_bytes\tduration\n" +
+		"#types\ttime\tstring\tcount\tinterval\n" +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\t100\t1.500000\n" +
+		"1452684930.000000\tC7fIlMZDuRiqjpYbb\t-\t0.250000\n" +
+		"1452684955.100000\tCHhAvVGS1DHFjwGM9\t50\t2.000000\n" +
+		"1452685080.000000\tClEkJM2Vm5giqnMf4h\t25\t-\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times, values, err := parser.TimeSeries("orig_bytes", time.Minute)
+	assert.Nil(err)
+	assert.Equal(times, []time.Time{
+		time.Unix(1452684900, 0).UTC(),
+		time.Unix(1452684960, 0).UTC(),
+		time.Unix(1452685020, 0).UTC(),
+		time.Unix(1452685080, 0).UTC(),
+	}, "bucketed the entries incorrectly")
+	assert.Equal(values, []float64{150, 0, 0, 25}, "summed the values incorrectly")
+
+	_, values, err = parser.TimeSeries("duration", time.Hour)
+	assert.Nil(err)
+	assert.Equal(values, []float64{3.75}, "intervals should be summed in seconds")
+
+	_, _, err = parser.TimeSeries("uid", time.Minute)
+	assert.NotNil(err, "expected an error for a field that isn't a number")
+
+	_, _, err = parser.TimeSeries("resp_bytes", time.Minute)
+	assert.NotNil(err, "expected an error for a missing field")
+}
+
+func TestTimeSeriesOutlier(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\torig_bytes\n" +
+		"#types\ttime\tcount\n" +
+		"0.000000\t10\n" +
+		"1452684903.908400\t100\n" +
+		"1452684955.100000\t50\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = parser.TimeSeries("orig_bytes", time.Second)
+	assert.NotNil(err, "expected an error for buckets spanning decades")
+
+	// The buckets filled in count towards the bound
+	parser.SetMaxKeys(10)
+	parser.CreateErrorBuffer(10)
+	times, values, err := parser.TimeSeries("orig_bytes", time.Second)
+	assert.Nil(err)
+	assert.True(len(times) <= 10, "expected at most max keys buckets")
+	assert.Equal(len(values), len(times), "expected a value per bucket")
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	assert.Equal(sum, float64(160), "expected every value in the larger buckets")
+	if assert.Equal(len(parser.Errors), 1, "expected the bound to be reported") {
+		assert.IsType(<-parser.Errors, &MaxKeysError{})
+	}
+}
@@ -0,0 +1,177 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// timeSpanChunk is how many bytes TimeSpan reads at a time from the end of
+// the Bro log, looking for its last entry.
+const timeSpanChunk = 4096
+
+// TimeSpan returns the ts of the first and the last entries of the Bro log,
+// ex: to show the hour a log covers. The first entry is read from the top,
+// and the last by reading backward from the end of the log to its last
+// complete line, so the rest of the log is not read. Compressed logs,
+// framed logs and logs that can't be seeked are read whole.
+func (p *Parser) TimeSpan() (first, last time.Time, err error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return first, last, err
+	}
+
+	index, err := getIndex(h.fields, "ts")
+	if err != nil {
+		return first, last, err
+	}
+
+	tsOf := func(entry []string) (time.Time, bool) {
+		if index >= len(entry) {
+			return time.Time{}, false
+		}
+		ts, err := ParseTime(entry[index])
+		return ts, err == nil
+	}
+
+	file, size, seekable := p.openSeekable()
+	if file != nil {
+		defer file.Close()
+	}
+
+	found := false
+	err = p.eachEntry(func(lineNum int, entry []string) error {
+		ts, ok := tsOf(entry)
+		if !ok {
+			return nil
+		}
+		if !found {
+			first, found = ts, true
+		}
+		last = ts
+
+		// Only the first entry is needed when the last can be read backward
+		if seekable && !h.json {
+			return errStopEmitting
+		}
+		return nil
+	})
+	if err != nil && err != errStopEmitting {
+		return first, last, err
+	}
+
+	if !found {
+		return first, last, errors.New("No entries with a ts found")
+	}
+
+	if seekable && !h.json {
+		if ts, ok := p.lastEntryTs(file, size, tsOf); ok {
+			last = ts
+		}
+	}
+
+	return first, last, nil
+}
+
+// openSeekable opens the Bro log for reading from its end, if it can be.
+// Framed logs can only be split into lines from their start.
+func (p *Parser) openSeekable() (io.ReadSeekCloser, int64, bool) {
+
+	if p.opener != nil || isCompressed(p.filepath) || p.customFraming() {
+		return nil, 0, false
+	}
+
+	var file io.ReadCloser
+	var err error
+	if p.fsys != nil {
+		file, err = p.fsys.Open(p.filepath)
+	} else {
+		file, err = os.Open(p.filepath)
+	}
+	if err != nil {
+		return nil, 0, false
+	}
+
+	seeker, ok := file.(io.ReadSeekCloser)
+	if !ok {
+		file.Close()
+		return nil, 0, false
+	}
+
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return nil, 0, false
+	}
+
+	return seeker, size, true
+}
+
+// lastEntryTs reads file backward from its end until it finds the last
+// complete entry with a ts.
+func (p *Parser) lastEntryTs(file io.ReadSeeker, size int64, tsOf func([]string) (time.Time, bool)) (time.Time, bool) {
+
+	var last time.Time
+	found := scanBackward(file, size, func(line string) bool {
+		if line == "" || line[0] == '#' {
+			return false
+		}
+		ts, ok := tsOf(p.splitEntry(line))
+		if ok {
+			last = ts
+		}
+		return ok
+	})
+
+	return last, found
+}
+
+// scanBackward calls fn with the complete lines of file, from its last line
+// to its first, reading it in chunks from its end. It stops as soon as fn
+// returns true, and reports whether it did.
+func scanBackward(file io.ReadSeeker, size int64, fn func(line string) bool) bool {
+
+	var tail []byte
+	end := size
+	for last := true; end > 0; last = false {
+		start := end - timeSpanChunk
+		if start < 0 {
+			start = 0
+		}
+
+		chunk := make([]byte, end-start)
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			return false
+		}
+		if _, err := io.ReadFull(file, chunk); err != nil {
+			return false
+		}
+		end = start
+
+		lines := bytes.Split(append(chunk, tail...), []byte{'\n'})
+
+		// A last line without a newline may still be being written
+		if last {
+			lines = lines[:len(lines)-1]
+		}
+
+		// Unless the start of the log was reached, the first line is partial,
+		// and is read with the next chunk
+		tail = nil
+		if start > 0 {
+			tail = lines[0]
+			lines = lines[1:]
+		}
+
+		for i := len(lines) - 1; i >= 0; i-- {
+			if fn(string(bytes.TrimSuffix(lines[i], []byte{'\r'}))) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package parse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSpan(t *testing.T) {
+	assert := assert.New(t)
+
+	// Enough entries for the last one to be found across chunks
+	var log strings.Builder
+	log.WriteString(followHeader)
+	log.WriteString("1452684903.908400\tCbOiIv2wbbH7F25W21\ttcp\n")
+	for i := 0; i < 500; i++ {
+		log.WriteString("1452684904.000000\tC7fIlMZDuRiqjpYbb\tudp\n")
+	}
+	log.WriteString("1452688503.500000\tCxPe2flVpE5gerpQc4\ttcp\n")
+	log.WriteString("#close\t2016-01-13-12-35-03\n")
+	log.WriteString("1452688504.000000\tCJ3mC")
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(log.String())}}
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, last, err := parser.TimeSpan()
+	assert.Nil(err)
+	assert.Equal(first, time.Unix(1452684903, 908400000).UTC(), "first ts is incorrect")
+	assert.Equal(last, time.Unix(1452688503, 500000000).UTC(), "last ts should be of the last complete entry")
+
+	// Streams are read whole, and give the same span
+	parser, err = NewParserReader("conn.log", func(offset int64) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(fsys["conn.log"].Data)), nil
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamFirst, streamLast, err := parser.TimeSpan()
+	assert.Nil(err)
+	assert.Equal(streamFirst, first, "first ts of streams is incorrect")
+	assert.Equal(streamLast, last, "last ts of streams is incorrect")
+}
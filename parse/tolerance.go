@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"strconv"
+)
+
+// toleranceMinRows is how many rows are read before the error rate of a
+// field can end parsing, so that a bad value early in the log doesn't.
+const toleranceMinRows = 100
+
+// FieldErrorRateError ends parsing when the fraction of rows with an
+// invalid value for a field is over its tolerance.
+type FieldErrorRateError struct {
+	Field       string
+	Errors      int
+	Rows        int
+	MaxFraction float64
+}
+
+func (e *FieldErrorRateError) Error() string {
+	return "Field " + e.Field + " has invalid values in " + strconv.Itoa(e.Errors) + " of " + strconv.Itoa(e.Rows) +
+		" rows, over the tolerance of " + strconv.FormatFloat(e.MaxFraction, 'f', -1, 64)
+}
+
+// SetFieldErrorTolerance tolerates values of field that don't match its
+// type in the #types header, ex: a flaky count written by a broken script,
+// in up to maxFraction of the rows (ex: 0.01 for 1%). Invalid values are
+// emitted as the unset value of the log. Once more than maxFraction of
+// the rows have an invalid value, parsing ends with a FieldErrorRateError,
+// so that a corrupted log is still caught. The rate is checked from the
+// first 100 rows on, and at the end of the log. field must be one of the
+// fields parsed.
+func (p *Parser) SetFieldErrorTolerance(field string, maxFraction float64) {
+	if p.errorTolerances == nil {
+		p.errorTolerances = make(map[string]float64)
+	}
+	p.errorTolerances[field] = maxFraction
+}
+
+// fieldTolerance is the tolerance of a field, resolved to its index and
+// type, and its count of invalid values.
+type fieldTolerance struct {
+	field       string
+	index       int
+	broType     string
+	maxFraction float64
+	errors      int
+}
+
+// toleranceCheck counts the invalid values of the tolerated fields.
+type toleranceCheck struct {
+	fields []*fieldTolerance
+	rows   int
+}
+
+// resolveTolerances returns the check of the tolerated fields, or nil if
+// there are none.
+func (p *Parser) resolveTolerances() (*toleranceCheck, error) {
+
+	if len(p.errorTolerances) == 0 {
+		return nil, nil
+	}
+
+	types, err := p.rowTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	check := new(toleranceCheck)
+	for field, maxFraction := range p.errorTolerances {
+		index, err := getIndex(p.fields, field)
+		if err != nil {
+			return nil, err
+		}
+		check.fields = append(check.fields, &fieldTolerance{field: field, index: index, broType: types[index], maxFraction: maxFraction})
+	}
+	return check, nil
+}
+
+// checkRow returns a row with the invalid values of the tolerated fields
+// unset, or an error once a field is over its tolerance.
+func (t *toleranceCheck) checkRow(p *Parser, row []string) ([]string, error) {
+
+	if t == nil {
+		return row, nil
+	}
+
+	t.rows++
+	checked, copied := row, false
+	for _, tolerance := range t.fields {
+		if tolerance.index >= len(row) || row[tolerance.index] == p.markers.Unset {
+			continue
+		}
+		if _, err := p.coerce(tolerance.broType, row[tolerance.index]); err == nil {
+			continue
+		}
+
+		tolerance.errors++
+		if !copied {
+			checked, copied = append([]string{}, row...), true
+		}
+		checked[tolerance.index] = p.markers.Unset
+	}
+
+	if t.rows < toleranceMinRows {
+		return checked, nil
+	}
+	return checked, t.exceeded()
+}
+
+// exceeded returns an error if a field is over its tolerance.
+func (t *toleranceCheck) exceeded() error {
+
+	if t == nil || t.rows == 0 {
+		return nil
+	}
+
+	for _, tolerance := range t.fields {
+		if float64(tolerance.errors)/float64(t.rows) > tolerance.maxFraction {
+			return &FieldErrorRateError{Field: tolerance.field, Errors: tolerance.errors, Rows: t.rows, MaxFraction: tolerance.maxFraction}
+		}
+	}
+	return nil
+}
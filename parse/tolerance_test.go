@@ -0,0 +1,64 @@
+package parse
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// toleranceLog returns a log of rows rows, with an invalid orig_bytes
+// every invalidEvery rows.
+func toleranceLog(rows, invalidEvery int) []byte {
+	var log strings.Builder
+	log.WriteString("#separator \\x09\n#fields\tuid\torig_bytes\n#types\tstring\tcount\n")
+	for i := 0; i < rows; i++ {
+		value := strconv.Itoa(i)
+		if i%invalidEvery == 0 {
+			value = "garbage"
+		}
+		log.WriteString("C" + strconv.Itoa(i) + "\t" + value + "\n")
+	}
+	return []byte(log.String())
+}
+
+func TestSetFieldErrorTolerance(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"flaky.log":   {Data: toleranceLog(200, 50)},
+		"corrupt.log": {Data: toleranceLog(200, 2)},
+	}
+
+	parser, err := NewParserFS(fsys, "flaky.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFieldErrorTolerance("orig_bytes", 0.05)
+
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(len(rows), 200, "expected every row within the tolerance")
+	assert.Equal(rows[0], []string{"C0", "-"}, "expected invalid values to be unset")
+	assert.Equal(rows[1], []string{"C1", "1"}, "expected valid values kept")
+
+	parser, err = NewParserFS(fsys, "corrupt.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	parser.SetFieldErrorTolerance("orig_bytes", 0.05)
+
+	rows, err = parser.ReadAll()
+	rateErr, ok := err.(*FieldErrorRateError)
+	assert.True(ok, "expected parsing to end over the tolerance")
+	assert.Equal(rateErr.Rows, toleranceMinRows, "expected parsing to end once the rate is checked")
+	assert.Nil(rows)
+}
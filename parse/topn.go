@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// TopN returns the n entries of the Bro log with the largest values of
+// field, in descending order, ex: the biggest data transfers by orig_bytes.
+// Unlike SortBy only n entries are held in memory at once. Values are
+// compared as with SortBy, and entries with equal values keep their
+// original order.
+func (p *Parser) TopN(field string, n int, numeric bool) ([][]string, error) {
+
+	if n <= 0 {
+		return nil, errors.New("N must be positive")
+	}
+
+	allFields, err := p.logFields()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := getIndex(allFields, field)
+	if err != nil {
+		return nil, err
+	}
+
+	top := &topEntries{numeric: numeric}
+	var seq int
+
+	err = p.eachEntry(func(lineNum int, entry []string) error {
+		if index >= len(entry) {
+			return nil
+		}
+
+		candidate := topEntry{entry: entry, value: entry[index], seq: seq}
+		if numeric {
+			candidate.key = numericKey(entry[index])
+		}
+		seq++
+
+		if top.Len() < n {
+			heap.Push(top, candidate)
+		} else if top.less(top.entries[0], candidate) {
+			top.entries[0] = candidate
+			heap.Fix(top, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([][]string, top.Len())
+	for i := len(entries) - 1; i >= 0; i-- {
+		entries[i] = heap.Pop(top).(topEntry).entry
+	}
+
+	return entries, nil
+}
+
+// topEntry is an entry held by TopN, with its sort key and position.
+type topEntry struct {
+	entry []string
+	value string
+	key   float64
+	seq   int
+}
+
+// topEntries is a min-heap of entries, the smallest being the first
+// to be replaced by a larger entry.
+type topEntries struct {
+	entries []topEntry
+	numeric bool
+}
+
+// less orders entries by value, later entries being smaller on ties.
+func (t *topEntries) less(a, b topEntry) bool {
+	if t.numeric {
+		if a.key != b.key {
+			return a.key < b.key
+		}
+	} else if a.value != b.value {
+		return a.value < b.value
+	}
+	return a.seq > b.seq
+}
+
+func (t *topEntries) Len() int           { return len(t.entries) }
+func (t *topEntries) Less(i, j int) bool { return t.less(t.entries[i], t.entries[j]) }
+func (t *topEntries) Swap(i, j int)      { t.entries[i], t.entries[j] = t.entries[j], t.entries[i] }
+func (t *topEntries) Push(x interface{}) { t.entries = append(t.entries, x.(topEntry)) }
+func (t *topEntries) Pop() interface{} {
+	last := t.entries[len(t.entries)-1]
+	t.entries = t.entries[:len(t.entries)-1]
+	return last
+}
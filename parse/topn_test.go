@@ -0,0 +1,60 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopN(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\torig_bytes\n" +
+		"#types\tstring\tcount\n" +
+		"C1\t900\n" +
+		"C2\t-\n" +
+		"C3\t1000\n" +
+		"C4\t900\n" +
+		"C5\t80\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uids := func(entries [][]string) []string {
+		var uids []string
+		for _, entry := range entries {
+			uids = append(uids, entry[0])
+		}
+		return uids
+	}
+
+	entries, err := parser.TopN("orig_bytes", 3, true)
+	assert.Nil(err)
+	assert.Equal(uids(entries), []string{"C3", "C1", "C4"}, "expected the largest values, ties in order")
+
+	entries, err = parser.TopN("orig_bytes", 2, false)
+	assert.Nil(err)
+	assert.Equal(uids(entries), []string{"C1", "C4"}, "compared as strings incorrectly")
+
+	entries, err = parser.TopN("orig_bytes", 10, true)
+	assert.Nil(err)
+	assert.Equal(uids(entries), []string{"C3", "C1", "C4", "C5", "C2"}, "expected every entry when n is larger than the log")
+
+	// NaN is never among the largest values
+	fsys["nan.log"] = &fstest.MapFile{Data: []byte("#separator \\x09\n#fields\tuid\tduration\n#types\tstring\tinterval\n" +
+		"C1\tNaN\nC2\t1.0\nC3\tNaN\nC4\t3.0\nC5\t2.0\n")}
+	nan, err := NewParserFS(fsys, "nan.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = nan.TopN("duration", 3, true)
+	assert.Nil(err)
+	assert.Equal(uids(entries), []string{"C4", "C5", "C2"}, "expected NaN to be the smallest")
+
+	_, err = parser.TopN("orig_bytes", 0, true)
+	assert.NotNil(err, "expected an error for n of 0")
+}
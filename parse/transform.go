@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ConditionalParse wraps a Parse() function so that it is only applied to rows
+// matching pred. Rows that don't match are passed through unchanged.
+func ConditionalParse(pred func([]string) bool, parseFunc Parse) Parse {
+	return func(fields, row []string) ([]string, error) {
+		if !pred(row) {
+			return row, nil
+		}
+		return parseFunc(fields, row)
+	}
+}
+
+// ConnDirectionFields are the fields ConnDirections appends to the fields
+// of a conn.log row, in order.
+var ConnDirectionFields = []string{"direction", "bytes", "pkts"}
+
+// ConnDirections is a ParseMulti() function that explodes every connection
+// of a conn.log into one row per direction: "orig" with the orig_bytes and
+// orig_pkts of the connection, and "resp" with resp_bytes and resp_pkts.
+// Both rows are the values of the connection followed by the values of
+// ConnDirectionFields.
+func ConnDirections(fields, row []string) ([][]string, error) {
+
+	var indexes [4]int
+	for i, field := range []string{"orig_bytes", "orig_pkts", "resp_bytes", "resp_pkts"} {
+		index, err := getIndex(fields, field)
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(row) {
+			return nil, errors.New("Row is missing field: " + field)
+		}
+		indexes[i] = index
+	}
+
+	direction := func(name string, bytes, pkts int) []string {
+		directionRow := make([]string, 0, len(row)+len(ConnDirectionFields))
+		directionRow = append(directionRow, row...)
+		return append(directionRow, name, row[bytes], row[pkts])
+	}
+
+	return [][]string{
+		direction("orig", indexes[0], indexes[1]),
+		direction("resp", indexes[2], indexes[3]),
+	}, nil
+}
+
+// InterArrivalParse returns a Parse() function that appends a "gap" column
+// to the row: the seconds between the tsField (ex: "ts") of the row and that
+// of the row before it, formatted like a Bro interval, and 0 for the first
+// row. Rows with an unset or invalid ts get the unset value "-", and the
+// next gap is measured from the row before them.
+//
+// Unlike other Parse() functions it keeps state between rows, so a new one
+// should be created for every pass over a log, and it should not be shared
+// between parsers. In a chain it measures the gaps between the rows it is
+// called with: rows skipped by the filter or by ConditionalParse are not
+// counted, and neither are rows for which an earlier function in the chain
+// failed, since the chain stops there.
+func InterArrivalParse(tsField string) Parse {
+
+	var previous time.Time
+	var started bool
+	return func(fields, row []string) ([]string, error) {
+
+		index, err := getIndex(fields, tsField)
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(row) {
+			return nil, errors.New("Row is missing field: " + tsField)
+		}
+
+		gap := DefaultFieldMarkers.Unset
+		if ts, err := ParseTime(row[index]); err == nil {
+			if !started {
+				previous, started = ts, true
+			}
+			gap = strconv.FormatFloat(ts.Sub(previous).Seconds(), 'f', 6, 64)
+			previous = ts
+		}
+
+		return append(append([]string{}, row...), gap), nil
+	}
+}
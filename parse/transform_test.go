@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalParse(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []string{"id.orig_h", "proto"}
+
+	upper := func(fields, row []string) ([]string, error) {
+		return []string{row[0], strings.ToUpper(row[1])}, nil
+	}
+	isTCP := func(row []string) bool {
+		return row[1] == "tcp"
+	}
+
+	parseFunc := ConditionalParse(isTCP, upper)
+
+	row, err := parseFunc(fields, []string{"10.1.20.227", "tcp"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"10.1.20.227", "TCP"}, "matching row should be parsed")
+
+	row, err = parseFunc(fields, []string{"10.1.20.227", "udp"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"10.1.20.227", "udp"}, "non matching row should be unchanged")
+}
+
+func TestConnDirections(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\torig_bytes\tresp_bytes\torig_pkts\tresp_pkts\n" +
+		"#types\tstring\tcount\tcount\tcount\tcount\n" +
+		"CbOiIv2wbbH7F25W21\t100\t2000\t3\t4\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields([]string{"uid", "orig_bytes", "orig_pkts", "resp_bytes", "resp_pkts"})
+	parser.CreateBuffer(10)
+
+	go parser.BufferRowMulti(ConnDirections)
+
+	var rows [][]string
+	for row := range parser.Row {
+		rows = append(rows, row)
+	}
+
+	assert.Equal(rows, [][]string{
+		{"CbOiIv2wbbH7F25W21", "100", "3", "2000", "4", "orig", "100", "3"},
+		{"CbOiIv2wbbH7F25W21", "100", "3", "2000", "4", "resp", "2000", "4"},
+	}, "expected a row per direction")
+
+	_, err = ConnDirections([]string{"uid"}, []string{"CbOiIv2wbbH7F25W21"})
+	assert.NotNil(err, "expected an error without byte and packet fields")
+}
+
+func TestInterArrivalParse(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"ts", "proto"})
+
+	rows, err := parser.ReadAll(InterArrivalParse("ts"))
+	assert.Nil(err)
+	assert.Equal(rows, [][]string{
+		{"1452684903.908400", "tcp", "0.000000"},
+		{"1452684905.100000", "udp", "1.191600"},
+		{"1452684904.500000", "tcp", "-0.600000"},
+		{"1452684906.000000", "icmp", "1.500000"},
+	}, "computed the gaps between rows incorrectly")
+
+	parseFunc := InterArrivalParse("ts")
+	fields := []string{"ts"}
+	row, err := parseFunc(fields, []string{"-"})
+	assert.Nil(err)
+	assert.Equal(row, []string{"-", "-"}, "expected an unset gap for an unset ts")
+
+	_, err = parseFunc([]string{"uid"}, []string{"C1"})
+	assert.NotNil(err, "expected an error for a missing field")
+}
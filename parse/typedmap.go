@@ -0,0 +1,103 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// FieldValue is a field of a row and its typed value.
+type FieldValue struct {
+	Field string
+	Value interface{}
+}
+
+// TypedMap is a row as its fields and typed values, in the order of the
+// fields. It is marshalled as a JSON object keeping that order.
+type TypedMap []FieldValue
+
+// Get returns the value of a field, and whether the row has the field.
+func (m TypedMap) Get(field string) (interface{}, bool) {
+	for _, fieldValue := range m {
+		if fieldValue.Field == field {
+			return fieldValue.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON marshals the row as a JSON object with its fields in order.
+func (m TypedMap) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, fieldValue := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(fieldValue.Field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(fieldValue.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// CreateTypedMapBuffer initializes the p.TypedRowMap channel pushed into
+// by BufferTypedRowMap.
+func (p *Parser) CreateTypedMapBuffer(bufferSize int) {
+	p.TypedRowMap = make(chan TypedMap, bufferSize)
+}
+
+// BufferTypedRowMap is BufferRow, but rows are pushed into p.TypedRowMap
+// as a TypedMap, with values converted by TypedRow. Rows that fail to be
+// converted are reported and skipped.
+func (p *Parser) BufferTypedRowMap() {
+
+	if p.TypedRowMap == nil {
+		fmt.Println("Initialize nil channel, via CreateTypedMapBuffer()")
+		return
+	}
+
+	err := p.eachSelectedRow(func(lineNum int, row []string) error {
+		typed, err := p.TypedRow(row)
+		if err != nil {
+			p.reportError(err)
+			return nil
+		}
+
+		typedMap := make(TypedMap, 0, len(typed))
+		for i, value := range typed {
+			if value == nil && p.omitUnset {
+				continue
+			}
+			typedMap = append(typedMap, FieldValue{Field: p.fields[i], Value: value})
+		}
+		if p.sourceFields {
+			typedMap = p.withSourceFields(typedMap)
+		}
+
+		p.waitIfPaused()
+		p.TypedRowMap <- typedMap
+		return nil
+	})
+	if err != nil {
+		p.reportError(err)
+	}
+
+	close(p.TypedRowMap)
+	if p.Errors != nil {
+		close(p.Errors)
+	}
+}
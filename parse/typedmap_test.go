@@ -0,0 +1,46 @@
+package parse
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferTypedRowMap(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\tid.resp_p\torig_bytes\ttunnel_parents\n" +
+		"#types\tstring\tport\tcount\tset[string]\n" +
+		"CbOiIv2wbbH7F25W21\t443\t100\t(empty)\n" +
+		"C7fIlMZDuRiqjpYbb\thttps\t200\t-\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser.SetFields([]string{"uid", "orig_bytes", "id.resp_p"})
+	parser.CreateTypedMapBuffer(10)
+	parser.CreateErrorBuffer(10)
+
+	go parser.BufferTypedRowMap()
+
+	var rows []TypedMap
+	for row := range parser.TypedRowMap {
+		rows = append(rows, row)
+	}
+
+	assert.Equal(len(rows), 1, "rows failing to convert should be skipped")
+	assert.Equal(len(parser.Errors), 1, "expected the invalid port to be reported")
+
+	value, ok := rows[0].Get("id.resp_p")
+	assert.True(ok)
+	assert.Equal(value, uint16(443), "expected typed values")
+
+	encoded, err := json.Marshal(rows[0])
+	assert.Nil(err)
+	assert.Equal(string(encoded), `{"uid":"CbOiIv2wbbH7F25W21","orig_bytes":100,"id.resp_p":443}`, "expected fields in order")
+}
@@ -0,0 +1,321 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTime converts a Bro time value, seconds since the epoch with an
+// optional fraction (ex: "1452684903.908400"), into a time.Time. Times
+// before the epoch are negative, fraction included: "-1.5" is 1.5 seconds
+// before it.
+func ParseTime(s string) (time.Time, error) {
+
+	seconds, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		seconds, fraction = s[:i], s[i+1:]
+	}
+
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid time value: " + s)
+	}
+
+	var nsec int64
+	if fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		digits, err := strconv.ParseUint(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
+			return time.Time{}, errors.New("Invalid time value: " + s)
+		}
+		nsec = int64(digits)
+	}
+
+	// The sign applies to the fraction too, and is the only one of "-0.5"
+	if strings.HasPrefix(s, "-") {
+		nsec = -nsec
+	}
+
+	return time.Unix(sec, nsec).UTC(), nil
+}
+
+// ParseInterval converts a Bro interval value, seconds with an optional
+// fraction (ex: "3.500000"), into a time.Duration. Intervals may be
+// negative. The unset value "-" is reported as an error, as it has no
+// duration.
+func ParseInterval(s string) (time.Duration, error) {
+
+	if s == "-" {
+		return 0, errors.New("Interval value is unset")
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	seconds, fraction := strings.TrimPrefix(s, "-"), ""
+	if i := strings.IndexByte(seconds, '.'); i >= 0 {
+		seconds, fraction = seconds[:i], seconds[i+1:]
+	}
+
+	// Only the leading "-" is a sign, the digits themselves are unsigned
+	sec, err := strconv.ParseUint(seconds, 10, 63)
+	if err != nil {
+		return 0, errors.New("Invalid interval value: " + s)
+	}
+
+	var nsec uint64
+	if fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		nsec, err = strconv.ParseUint(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
+			return 0, errors.New("Invalid interval value: " + s)
+		}
+	}
+
+	duration := time.Duration(sec)*time.Second + time.Duration(nsec)
+	if negative {
+		duration = -duration
+	}
+	return duration, nil
+}
+
+// FieldKinds returns the Go kind of every field of the Bro log, based on its
+// #types header. Bro types map to the kinds they are converted to:
+// bool is a Bool, count a Uint64, int and interval (time.Duration) are
+// Int64s, double is a Float64, port is a Uint16, time is a Struct
+// (time.Time), sets and vectors are Slices, and addr, subnet, enum, string
+// and any other type are Strings.
+func (p *Parser) FieldKinds() (map[string]reflect.Kind, error) {
+
+	fields, types, err := p.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	if types == nil {
+		return nil, errors.New("No types header found")
+	}
+
+	kinds := make(map[string]reflect.Kind, len(fields))
+	for i, field := range fields {
+		if field == "" || i >= len(types) {
+			continue
+		}
+		kinds[field] = kindOf(types[i])
+	}
+
+	return kinds, nil
+}
+
+// kindOf returns the Go kind a Bro type is converted to.
+func kindOf(broType string) reflect.Kind {
+
+	if isContainerType(broType) {
+		return reflect.Slice
+	}
+
+	switch broType {
+	case "bool":
+		return reflect.Bool
+	case "count":
+		return reflect.Uint64
+	case "int", "interval":
+		return reflect.Int64
+	case "double":
+		return reflect.Float64
+	case "port":
+		return reflect.Uint16
+	case "time":
+		return reflect.Struct
+	}
+
+	return reflect.String
+}
+
+// isContainerType reports whether a Bro type is a set or vector, ex: set[addr].
+func isContainerType(broType string) bool {
+	return strings.HasPrefix(broType, "set[") || strings.HasPrefix(broType, "vector[")
+}
+
+// containerElementType returns the type of the elements of a set or vector,
+// ex: addr for set[addr].
+func containerElementType(broType string) string {
+	start := strings.IndexByte(broType, '[')
+	return strings.TrimSuffix(broType[start+1:], "]")
+}
+
+// RegisterTypeCoercer registers a function converting values of a Bro type,
+// ex: the enum of a custom Zeek script, into a Go value. TypedRow consults
+// registered coercers before the built-in conversions, and for sets and
+// vectors the coercer of the element type is used for each element.
+func (p *Parser) RegisterTypeCoercer(broType string, fn func(string) (interface{}, error)) {
+	if p.coercers == nil {
+		p.coercers = make(map[string]func(string) (interface{}, error))
+	}
+	p.coercers[broType] = fn
+}
+
+// TypedRow converts the values of a row, as pushed by BufferRow, into Go
+// values based on the #types header. The Go types match the kinds returned
+// by FieldKinds, with addr values in their canonical form. Unset values
+// are nil, and sets and vectors are []interface{}.
+func (p *Parser) TypedRow(row []string) ([]interface{}, error) {
+
+	types, err := p.rowTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(row) != len(types) {
+		return nil, errors.New("Row has " + strconv.Itoa(len(row)) + " values but there are " + strconv.Itoa(len(types)) + " types")
+	}
+
+	typed := make([]interface{}, len(row))
+	for i, value := range row {
+		typed[i], err = p.coerce(types[i], value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return typed, nil
+}
+
+// rowTypes returns the Bro type of every field in p.fields.
+func (p *Parser) rowTypes() ([]string, error) {
+
+	if p.types != nil {
+		return p.types, nil
+	}
+
+	if p.fields == nil {
+		return nil, errors.New("No fields parsed")
+	}
+
+	fields, types, err := p.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if types == nil {
+		return nil, errors.New("No types header found")
+	}
+
+	rowTypes := make([]string, len(p.fields))
+	for i, field := range p.fields {
+		index, err := getIndex(fields, field)
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(types) {
+			return nil, errors.New("No type declared for field: " + field)
+		}
+		rowTypes[i] = types[index]
+	}
+
+	p.types = rowTypes
+	return rowTypes, nil
+}
+
+// coerce converts a single value of a Bro type.
+func (p *Parser) coerce(broType, value string) (interface{}, error) {
+	if p.humanizedNumbers && isNumericType(broType) {
+		if _, ok := p.coercers[broType]; !ok {
+			value = dehumanize(value)
+		}
+	}
+	return coerceWith(p.coercers, p.markers, broType, value)
+}
+
+// coerceWith converts a single value of a Bro type, using the conversion
+// registered for the type in coercers if there is one, and the markers of
+// its log for unset and empty values and sets.
+func coerceWith(coercers map[string]func(string) (interface{}, error), markers FieldMarkers, broType, value string) (interface{}, error) {
+
+	if fn, ok := coercers[broType]; ok {
+		return fn(value)
+	}
+
+	if value == markers.Unset {
+		return nil, nil
+	}
+
+	if isContainerType(broType) {
+		elements := []interface{}{}
+		if value == markers.Empty {
+			return elements, nil
+		}
+
+		elementType := containerElementType(broType)
+		for _, element := range strings.Split(value, markers.SetSeparator) {
+			typed, err := coerceWith(coercers, markers, elementType, element)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, typed)
+		}
+		return elements, nil
+	}
+
+	return coerceValue(markers, broType, value)
+}
+
+// coerceValue performs the built-in conversion of a value that isn't unset.
+func coerceValue(markers FieldMarkers, broType, value string) (interface{}, error) {
+
+	invalid := errors.New("Invalid " + broType + " value: " + value)
+
+	switch broType {
+	case "bool":
+		switch value {
+		case "T":
+			return true, nil
+		case "F":
+			return false, nil
+		}
+		return nil, invalid
+	case "count":
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	case "double":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	case "interval":
+		return ParseInterval(value)
+	case "port":
+		v, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, invalid
+		}
+		return uint16(v), nil
+	case "time":
+		return ParseTime(value)
+	case "addr":
+		addr, err := ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		return addr.String(), nil
+	}
+
+	if value == markers.Empty {
+		return "", nil
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldKinds(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds, err := parser.FieldKinds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(kinds["ts"], reflect.Struct, "time should be a time.Time")
+	assert.Equal(kinds["uid"], reflect.String, "string should be a string")
+	assert.Equal(kinds["id.orig_h"], reflect.String, "addr should be a string")
+	assert.Equal(kinds["id.orig_p"], reflect.Uint16, "port should be a uint16")
+	assert.Equal(kinds["proto"], reflect.String, "enum should be a string")
+
+	assert.Equal(kindOf("count"), reflect.Uint64)
+	assert.Equal(kindOf("int"), reflect.Int64)
+	assert.Equal(kindOf("double"), reflect.Float64)
+	assert.Equal(kindOf("interval"), reflect.Int64)
+	assert.Equal(kindOf("bool"), reflect.Bool)
+	assert.Equal(kindOf("set[addr]"), reflect.Slice)
+	assert.Equal(kindOf("vector[interval]"), reflect.Slice)
+
+	parser, err = NewParser("../sample_logs/headerless.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.FieldKinds()
+	assert.NotNil(err, "expected an error for a log without a #types header")
+}
+
+func TestTypedRow(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tid.orig_h\tid.orig_p\tduration\torig_bytes\tlocal_orig\ttunnel_parents\tproto\n" +
+		"#types\ttime\taddr\tport\tinterval\tcount\tbool\tset[string]\tenum\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+
+	typed, err := parser.TypedRow([]string{"1452684903.908400", "::ffff:10.1.20.227", "443", "1.5", "100", "T", "C1,C2", "tcp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(typed[0], time.Unix(1452684903, 908400000).UTC(), "converted time incorrectly")
+	assert.Equal(typed[1], "10.1.20.227", "converted addr incorrectly")
+	assert.Equal(typed[2], uint16(443), "converted port incorrectly")
+	assert.Equal(typed[3], 1500*time.Millisecond, "converted interval incorrectly")
+	assert.Equal(typed[4], uint64(100), "converted count incorrectly")
+	assert.Equal(typed[5], true, "converted bool incorrectly")
+	assert.Equal(typed[6], []interface{}{"C1", "C2"}, "converted set incorrectly")
+	assert.Equal(typed[7], "tcp", "converted enum incorrectly")
+
+	typed, err = parser.TypedRow([]string{"-", "-", "-", "-", "-", "-", "(empty)", "-"})
+	assert.Nil(err)
+	assert.Nil(typed[0], "unset values should be nil")
+	assert.Equal(typed[6], []interface{}{}, "empty sets should be empty")
+
+	_, err = parser.TypedRow([]string{"1452684903.908400", "10.1.20.227", "http", "1.5", "100", "T", "C1", "tcp"})
+	assert.NotNil(err, "expected an error for an invalid port")
+}
+
+func TestRegisterTypeCoercer(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type protocol int
+	parser.RegisterTypeCoercer("enum", func(value string) (interface{}, error) {
+		if value == "tcp" {
+			return protocol(6), nil
+		}
+		return protocol(0), nil
+	})
+
+	parser.SetFields([]string{"uid", "proto"})
+	typed, err := parser.TypedRow([]string{"CbOiIv2wbbH7F25W21", "tcp"})
+	assert.Nil(err)
+	assert.Equal(typed[1], protocol(6), "registered coercer should be used")
+}
+
+func TestParseInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expected := range map[string]time.Duration{
+		"3.500000":     3500 * time.Millisecond,
+		"0.000001":     time.Microsecond,
+		"12":           12 * time.Second,
+		"-1.250000":    -1250 * time.Millisecond,
+		"1.1234567891": time.Second + 123456789,
+	} {
+		duration, err := ParseInterval(value)
+		assert.Nil(err)
+		assert.Equal(duration, expected, "parsed "+value+" incorrectly")
+	}
+
+	for _, value := range []string{"-", "", "abc", "1.-5", "+2", "--1", "-+1", "1.+5"} {
+		_, err := ParseInterval(value)
+		assert.NotNil(err, "expected an error for "+value)
+	}
+}
@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"errors"
+	"strings"
+)
+
+// maxTypeErrorSamples is the number of offending values kept per field.
+const maxTypeErrorSamples = 5
+
+// TypeReport is the result of ValidateTypes. Fields maps the fields with
+// values that don't match their declared type to their errors.
+type TypeReport struct {
+	Rows   int
+	Fields map[string]*TypeErrors
+}
+
+// Valid reports whether every value matched its declared type.
+func (r *TypeReport) Valid() bool {
+	return len(r.Fields) == 0
+}
+
+// TypeErrors counts the values of a field that don't match its declared
+// type, keeping the first few offending values as samples. For sets and
+// vectors, Elements holds the first few elements that don't match the
+// element type, ex: "https" for a vector[port] value of "80,https".
+type TypeErrors struct {
+	Type     string
+	Count    int
+	Samples  []string
+	Elements []string
+}
+
+// ValidateTypes checks that every value of the Bro log can be converted
+// to the type declared by the #types header, the same way TypedRow does,
+// ex: that port values are numbers and time values are valid epochs.
+// Sets and vectors are checked element by element, so that a corrupt
+// element of a container is reported too. Only the values of entries are
+// checked, not how many there are.
+func (p *Parser) ValidateTypes() (*TypeReport, error) {
+
+	fields, types, err := p.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if types == nil {
+		return nil, errors.New("No types header found")
+	}
+
+	report := &TypeReport{Fields: make(map[string]*TypeErrors)}
+
+	err = p.eachEntry(func(lineNum int, entry []string) error {
+		report.Rows++
+
+		for i, value := range entry {
+			if i >= len(fields) || i >= len(types) {
+				break
+			}
+
+			if _, err := p.coerce(types[i], value); err == nil {
+				continue
+			}
+
+			typeErrors, ok := report.Fields[fields[i]]
+			if !ok {
+				typeErrors = &TypeErrors{Type: types[i]}
+				report.Fields[fields[i]] = typeErrors
+			}
+			typeErrors.Count++
+			if len(typeErrors.Samples) < maxTypeErrorSamples {
+				typeErrors.Samples = append(typeErrors.Samples, value)
+			}
+			if element, ok := p.invalidElement(types[i], value); ok && len(typeErrors.Elements) < maxTypeErrorSamples {
+				typeErrors.Elements = append(typeErrors.Elements, element)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// invalidElement returns the first element of a set or vector value that
+// doesn't match the element type of the container.
+func (p *Parser) invalidElement(broType, value string) (string, bool) {
+
+	if !isContainerType(broType) || value == p.markers.Unset || value == p.markers.Empty {
+		return "", false
+	}
+	if _, ok := p.coercers[broType]; ok {
+		return "", false
+	}
+
+	elementType := containerElementType(broType)
+	for _, element := range strings.Split(value, p.markers.SetSeparator) {
+		if _, err := p.coerce(elementType, element); err != nil {
+			return element, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tts\tuid\tid.resp_p\n" +
+		"#types\ttime\tstring\tport\n" +
+		"1452684903.908400\tCbOiIv2wbbH7F25W21\t443\n" +
+		"yesterday\tC7fIlMZDuRiqjpYbb\thttps\n" +
+		"1452684905.908400\tCxPe2flVpE5gerpQc4\t-\n" +
+		"1452684906.908400\tCJ3mCc3ztdbXsVs6Gb\t80/tcp\n")}}
+
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := parser.ValidateTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(report.Rows, 4, "validated the wrong number of rows")
+	assert.False(report.Valid(), "expected the corrupt values to be reported")
+	assert.Equal(report.Fields["ts"], &TypeErrors{Type: "time", Count: 1, Samples: []string{"yesterday"}}, "reported ts incorrectly")
+	assert.Equal(report.Fields["id.resp_p"], &TypeErrors{Type: "port", Count: 2, Samples: []string{"https", "80/tcp"}}, "reported id.resp_p incorrectly")
+	assert.Nil(report.Fields["uid"], "strings should always be valid")
+}
+
+func TestValidateContainerTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"dns.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\tanswers\tresp_ports\n" +
+		"#types\tstring\tset[addr]\tvector[port]\n" +
+		"CbOiIv2wbbH7F25W21\t10.1.20.227,204.238.149.187\t53,443\n" +
+		"C7fIlMZDuRiqjpYbb\t10.1.20.227,10.1.300.1\t(empty)\n" +
+		"CxPe2flVpE5gerpQc4\t-\t80,https,8080\n")}}
+
+	parser, err := NewParserFS(fsys, "dns.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := parser.ValidateTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(report.Fields["answers"], &TypeErrors{Type: "set[addr]", Count: 1, Samples: []string{"10.1.20.227,10.1.300.1"}, Elements: []string{"10.1.300.1"}}, "reported answers incorrectly")
+	assert.Equal(report.Fields["resp_ports"], &TypeErrors{Type: "vector[port]", Count: 1, Samples: []string{"80,https,8080"}, Elements: []string{"https"}}, "reported resp_ports incorrectly")
+}
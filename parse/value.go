@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Value returns the value of a field in a row pushed by BufferRow, without
+// tracking the index of the field. Indexes are looked up once and cached.
+func (p *Parser) Value(row []string, field string) (string, error) {
+
+	if p.valueIndex == nil {
+		p.valueIndex = make(map[string]int, len(p.fields))
+		for i, name := range p.fields {
+			if _, ok := p.valueIndex[name]; !ok {
+				p.valueIndex[name] = i
+			}
+		}
+	}
+
+	index, ok := p.valueIndex[field]
+	if !ok {
+		return "", errors.New("Unknown field: " + field)
+	}
+
+	if index >= len(row) {
+		return "", errors.New("Row has " + strconv.Itoa(len(row)) + " values, missing field: " + field)
+	}
+
+	return row[index], nil
+}
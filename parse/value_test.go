@@ -0,0 +1,34 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser(logpath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields([]string{"uid", "proto"})
+
+	row := []string{"CbOiIv2wbbH7F25W21", "tcp"}
+
+	value, err := parser.Value(row, "proto")
+	assert.Nil(err)
+	assert.Equal(value, "tcp", "returned the wrong value")
+
+	_, err = parser.Value(row, "service")
+	assert.NotNil(err, "expected an error for an unknown field")
+
+	_, err = parser.Value(row[:1], "proto")
+	assert.NotNil(err, "expected an error for a short row")
+
+	parser.SetFields([]string{"proto", "uid"})
+	value, err = parser.Value([]string{"udp", "C7fIlMZDuRiqjpYbb"}, "proto")
+	assert.Nil(err)
+	assert.Equal(value, "udp", "cached indexes should be reset with the fields")
+}
@@ -0,0 +1,78 @@
+package parse
+
+import "errors"
+
+// Variant is the style of Bro log, which differs subtly between Bro 2.x and
+// Zeek 3.x and later in headers and field names.
+type Variant int
+
+const (
+	// VariantUnknown is a log without anything specific to either style.
+	VariantUnknown Variant = iota
+	// VariantBro is a log written by Bro 2.x.
+	VariantBro
+	// VariantZeek is a log written by Zeek 3.x or later.
+	VariantZeek
+)
+
+func (v Variant) String() string {
+	switch v {
+	case VariantBro:
+		return "bro-2.x"
+	case VariantZeek:
+		return "zeek-3.x+"
+	}
+	return "unknown"
+}
+
+// zeekFields are fields, by log type, only written by Zeek.
+var zeekFields = map[string][]string{
+	"conn": {"ip_proto"},
+	"ssl":  {"ssl_history"},
+	"x509": {"fingerprint", "host_cert", "client_cert"},
+}
+
+// broFields are fields, by log type, only written by Bro 2.x.
+var broFields = map[string][]string{
+	"dhcp": {"assigned_ip", "trans_id"},
+}
+
+// DetectVariant classifies the Bro log as written by Bro 2.x or by Zeek 3.x
+// and later, from its header only. Zeek JSON logs carrying _path are Zeek,
+// otherwise fields that only one of them writes are looked for. Logs with
+// neither are VariantUnknown.
+func (p *Parser) DetectVariant() (Variant, error) {
+
+	h, err := p.readHeader()
+	if err != nil {
+		return VariantUnknown, err
+	}
+
+	if h.fields == nil {
+		return VariantUnknown, errors.New("No fields header found")
+	}
+
+	if h.json && h.path != "" {
+		return VariantZeek, nil
+	}
+
+	if hasAnyField(h.fields, zeekFields[h.path]) {
+		return VariantZeek, nil
+	}
+
+	if hasAnyField(h.fields, broFields[h.path]) {
+		return VariantBro, nil
+	}
+
+	return VariantUnknown, nil
+}
+
+// hasAnyField reports whether fields contains any of wanted.
+func hasAnyField(fields, wanted []string) bool {
+	for _, field := range wanted {
+		if _, err := getIndex(fields, field); err == nil {
+			return true
+		}
+	}
+	return false
+}
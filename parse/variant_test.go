@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectVariant(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"bro_dhcp.log":  {Data: []byte("#separator \\x09\n#path\tdhcp\n#fields\tts\tuid\tmac\tassigned_ip\tlease_time\ttrans_id\n")},
+		"zeek_conn.log": {Data: []byte("#separator \\x09\n#path\tconn\n#fields\tts\tuid\tproto\tip_proto\n")},
+		"zeek.json":     {Data: []byte(`{"_path":"conn","ts":1452684903.9084,"uid":"CbOiIv2wbbH7F25W21"}` + "\n")},
+		"conn.log":      {Data: []byte("#separator \\x09\n#path\tconn\n#fields\tts\tuid\tproto\n")},
+	}
+
+	expected := map[string]Variant{
+		"bro_dhcp.log":  VariantBro,
+		"zeek_conn.log": VariantZeek,
+		"zeek.json":     VariantZeek,
+		"conn.log":      VariantUnknown,
+	}
+
+	for path, variant := range expected {
+		parser, err := NewParserFS(fsys, path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		detected, err := parser.DetectVariant()
+		assert.Nil(err)
+		assert.Equal(detected, variant, "detected the wrong variant for "+path)
+	}
+
+	assert.Equal(VariantZeek.String(), "zeek-3.x+", "variant name is incorrect")
+}
@@ -0,0 +1,220 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// headerTimeFormat is the layout Bro uses for the #open and #close headers.
+const headerTimeFormat = "2006-01-02-15-04-05"
+
+// SetOutputSeparator sets the separator of the Bro logs written by Extract
+// and WriteLog, ex: "|" to convert a tab separated log. The #separator header
+// is rewritten, and values containing the separator have it escaped as
+// \xNN, the way Bro does, which parsers unescape. The separator can't be
+// the #set_separator of the log, since sets couldn't be told apart from
+// their values. By default the separator of the log is kept.
+func (p *Parser) SetOutputSeparator(separator string) {
+	p.outputSeparator = separator
+}
+
+// Extract writes a new Bro log to w, containing only the entries for which
+// pred returns true. pred is called with all the values of an entry. The
+// header of the original log, including its separator and types, is kept
+// as is and the log is ended with its original #close line, or with one
+// stamped with the current time if it had none.
+func (p *Parser) Extract(w io.Writer, pred func([]string) bool) error {
+
+	file, fileErr := p.open()
+	if fileErr != nil {
+		return fileErr
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(w)
+	h := &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+	p.useHeader(h)
+
+	var closeLine string
+
+	scanner, _ := p.newScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '#' {
+			if err := h.parseLine(line); err != nil {
+				return err
+			}
+			if err := p.checkOutputSeparator(h); err != nil {
+				return err
+			}
+			p.useHeader(h)
+			line = p.outputHeaderLine(h, line)
+
+			// #close is written once all the entries are written
+			if strings.HasPrefix(line, "#close") {
+				closeLine = line
+				continue
+			}
+		} else {
+			entry := p.splitEntry(line)
+			if !pred(entry) {
+				continue
+			}
+			if p.outputSeparator != "" {
+				line = p.outputEntry(h, entry)
+			}
+		}
+
+		p.writeLine(out, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if closeLine == "" {
+		closeLine = "#close" + p.outputSeparatorOf(h) + time.Now().Format(headerTimeFormat)
+	}
+	p.writeLine(out, closeLine)
+
+	return out.Flush()
+}
+
+// WriteLog writes entries, such as the ones returned by SortBy, to w as a
+// Bro log. The header of the original log is written first, and the log is
+// ended with a #close line stamped with the current time.
+func (p *Parser) WriteLog(w io.Writer, entries [][]string) error {
+
+	lines, h, err := p.headerLines()
+	if err != nil {
+		return err
+	}
+	if err := p.checkOutputSeparator(h); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+
+	// Header lines are rewritten with the separator in effect when read
+	lineHeader := &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+	for _, line := range lines {
+		if err := lineHeader.parseLine(line); err != nil {
+			return err
+		}
+		p.writeLine(out, p.outputHeaderLine(lineHeader, line))
+	}
+
+	for _, entry := range entries {
+		p.writeLine(out, p.outputEntry(h, entry))
+	}
+
+	p.writeLine(out, "#close"+p.outputSeparatorOf(h)+time.Now().Format(headerTimeFormat))
+
+	return out.Flush()
+}
+
+// headerLines returns the raw header lines at the top of the Bro log,
+// along with the parsed header.
+func (p *Parser) headerLines() ([]string, *header, error) {
+
+	file, fileErr := p.open()
+	if fileErr != nil {
+		return nil, nil, fileErr
+	}
+	defer file.Close()
+
+	h := &header{separator: defaultSeparator, collapse: p.collapseSeparators}
+	var lines []string
+
+	scanner, _ := p.newScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] != '#' {
+			break
+		}
+
+		if err := h.parseLine(line); err != nil {
+			return nil, nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return lines, h, nil
+}
+
+// checkOutputSeparator returns an error if the output separator is the set
+// separator of logs read with h.
+func (p *Parser) checkOutputSeparator(h *header) error {
+	if p.outputSeparator != "" && p.outputSeparator == h.markers.orDefault().SetSeparator {
+		return errors.New("Output separator is the set separator of the log: " + p.outputSeparator)
+	}
+	return nil
+}
+
+// outputSeparatorOf returns the separator logs read with h are written with.
+func (p *Parser) outputSeparatorOf(h *header) string {
+	if p.outputSeparator != "" {
+		return p.outputSeparator
+	}
+	return h.separator
+}
+
+// outputEntry joins the values of an entry with the output separator, or
+// the separator of the log when none is set. The separator is escaped in
+// the values, as splitEntry unescapes it.
+func (p *Parser) outputEntry(h *header, entry []string) string {
+
+	separator := p.outputSeparatorOf(h)
+	escaped := make([]string, len(entry))
+	for i, value := range entry {
+		escaped[i] = strings.ReplaceAll(value, separator, encodeSeparator(separator))
+	}
+	return strings.Join(escaped, separator)
+}
+
+// outputHeaderLine rewrites a header line, read with h, with the output
+// separator.
+func (p *Parser) outputHeaderLine(h *header, line string) string {
+
+	if p.outputSeparator == "" {
+		return line
+	}
+
+	key, value := h.directive(line)
+	if key == "separator" {
+		return "#separator " + encodeSeparator(p.outputSeparator)
+	}
+	if value == "" {
+		return "#" + key
+	}
+
+	return "#" + key + p.outputSeparator + p.outputEntry(h, strings.Split(value, h.separator))
+}
+
+// encodeSeparator escapes every byte of a separator as \xNN, the way Bro
+// writes it in the #separator header.
+func encodeSeparator(separator string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(separator); i++ {
+		fmt.Fprintf(&encoded, "\\x%02x", separator[i])
+	}
+	return encoded.String()
+}
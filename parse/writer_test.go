@@ -0,0 +1,178 @@
+package parse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	parser, err := NewParser("../sample_logs/unordered.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = parser.Extract(&out, func(entry []string) bool {
+		return entry[3] == "tcp"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(len(lines), 11, "expected the header, two entries and #close")
+	assert.Equal(lines[0], "#separator \\x09", "header should be kept")
+	assert.Equal(lines[7], "#types\ttime\tstring\taddr\tenum", "types should be kept")
+	assert.True(strings.HasSuffix(lines[8], "\ttcp"), "only matching entries should be written")
+	assert.True(strings.HasSuffix(lines[9], "\ttcp"), "only matching entries should be written")
+	assert.Equal(lines[10], "#close\t2016-01-13-07-00-00", "original #close should end the log")
+
+	// The extracted log must be parseable on its own
+	extracted, err := NewParserFS(fstest.MapFS{"tcp.log": {Data: out.Bytes()}}, "tcp.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := extracted.Cardinality("uid")
+	assert.Nil(err)
+	assert.Equal(count, 2, "extracted log should contain two entries")
+}
+
+func TestExtractAddsClose(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x20\n#fields ts proto\n1452684903.908400 tcp\n")}}
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = parser.Extract(&out, func([]string) bool { return true })
+	assert.Nil(err)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.True(strings.HasPrefix(lines[len(lines)-1], "#close "), "#close should use the declared separator")
+}
+
+func TestSetOutputSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	log := "#separator \\x09\n" +
+		"#set_separator\t,\n" +
+		"#fields\tuid\tservice\n" +
+		"#types\tstring\tset[string]\n" +
+		"C1\thttp,ssl\n" +
+		"C|2\tdns\n" +
+		"#close\t2016-01-13-07-00-00\n"
+	readAll := func(parser *Parser) [][]string {
+		fields, err := parser.ParseAllFields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser.SetFields(fields)
+		rows, err := parser.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte(log)}}
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := readAll(parser)
+	assert.Equal(original[1], []string{"C|2", "dns"}, "parsed the original log incorrectly")
+	parser.SetOutputSeparator("|")
+
+	var out bytes.Buffer
+	err = parser.Extract(&out, func(entry []string) bool { return true })
+	assert.Nil(err)
+	assert.Equal(out.String(), "#separator \\x7c\n"+
+		"#set_separator|,\n"+
+		"#fields|uid|service\n"+
+		"#types|string|set[string]\n"+
+		"C1|http,ssl\n"+
+		"C\\x7c2|dns\n"+
+		"#close|2016-01-13-07-00-00\n", "rewrote the log with the new separator incorrectly")
+
+	// The rewritten log is read back the same as the original
+	converted := fstest.MapFS{"conn.log": {Data: out.Bytes()}}
+	parser, err = NewParserFS(converted, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(readAll(parser), original, "expected the rewritten log to read back the same as the original")
+
+	entries, err := parser.SortBy("uid", false)
+	assert.Nil(err)
+
+	parser.SetOutputSeparator("\t")
+	out.Reset()
+	err = parser.WriteLog(&out, entries)
+	assert.Nil(err)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(lines[0], "#separator \\x09", "expected the separator header to be rewritten")
+	assert.Equal(lines[2], "#fields\tuid\tservice", "expected header lines to be rewritten")
+
+	written := fstest.MapFS{"conn.log": {Data: out.Bytes()}}
+	parser, err = NewParserFS(written, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(readAll(parser), original, "expected the written log to read back the same as the original")
+
+	// Sets can't be told apart from their values with the set separator
+	parser.SetOutputSeparator(",")
+	err = parser.Extract(&out, func(entry []string) bool { return true })
+	assert.NotNil(err, "expected an error for the set separator as the output separator")
+	err = parser.WriteLog(&out, entries)
+	assert.NotNil(err, "expected an error for the set separator as the output separator")
+}
+
+func TestWriteLogEscapesSeparator(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{"conn.log": {Data: []byte("#separator \\x09\n" +
+		"#fields\tuid\tuser_agent\n" +
+		"#types\tstring\tstring\n" +
+		"C1\tcurl\\x09tab\n")}}
+	parser, err := NewParserFS(fsys, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parser.SortBy("uid", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(entries, [][]string{{"C1", "curl\ttab"}}, "expected the separator to be unescaped")
+
+	var out bytes.Buffer
+	err = parser.WriteLog(&out, entries)
+	assert.Nil(err)
+	assert.Contains(out.String(), "C1\tcurl\\x09tab\n", "expected the separator to be escaped again")
+
+	// The written log reads back the same entry
+	written := fstest.MapFS{"conn.log": {Data: out.Bytes()}}
+	parser, err = NewParserFS(written, "conn.log", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := parser.ParseAllFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetFields(fields)
+	rows, err := parser.ReadAll()
+	assert.Nil(err)
+	assert.Equal(rows, entries, "expected the written log to read back the same entries")
+}